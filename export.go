@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aarongodin/pagebin/pkg/app"
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/store"
+
+	"github.com/rs/zerolog/log"
+)
+
+// export renders a version to static files and prints the report. An output ending in .tar.gz or .tgz is written as a
+// gzipped tarball, and any other output is a directory. The database file is locked while the server is running, so
+// use the GET /api/export endpoint to export from a live server. Exits with status 1 when any path failed to render.
+func export(ctx context.Context, rc *config.RuntimeConfig, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	version := flags.String("version", "", "\"next\", a draft name or a version UID to export instead of the current version")
+	out := flags.String("out", "pagebin-export", "directory or .tar.gz file to write to")
+	baseURL := flags.String("base-url", "", "URL that feeds and the sitemap link to when the site has no URL")
+	flags.Parse(args)
+
+	store, err := store.NewStore(rc)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to init DB")
+	}
+	defer store.Close(ctx)
+	svc, err := app.NewService(rc, store)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to init service")
+	}
+	load(ctx, svc)
+
+	var w app.ExportWriter = app.NewDirExportWriter(*out)
+	var closer io.Closer
+	if strings.HasSuffix(*out, ".tar.gz") || strings.HasSuffix(*out, ".tgz") {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create tarball")
+		}
+		defer f.Close()
+		tw := app.NewTarExportWriter(f)
+		w, closer = tw, tw
+	}
+
+	report, err := svc.Export(ctx, app.ExportOptions{Version: *version, BaseURL: *baseURL}, w)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to export")
+	}
+	if closer != nil {
+		if err := closer.Close(); err != nil {
+			log.Fatal().Err(err).Msg("failed to write tarball")
+		}
+	}
+	log.Info().
+		Str("version", report.Version.String()).
+		Str("out", *out).
+		Int("files", len(report.Files)).
+		Int("skipped", len(report.Skipped)).
+		Int("failures", len(report.Failures)).
+		Msg("export complete")
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal().Err(err).Msg("failed to write report")
+	}
+	if len(report.Failures) > 0 {
+		os.Exit(1)
+	}
+}
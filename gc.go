@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/aarongodin/pagebin/pkg/app"
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/store"
+
+	"github.com/rs/zerolog/log"
+)
+
+// gc runs garbage collection against the database file. The file is locked while the server is running, so use the
+// POST /api/gc endpoint to collect garbage on a live server.
+func gc(ctx context.Context, rc *config.RuntimeConfig, args []string) {
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would be deleted without deleting anything")
+	flags.Parse(args)
+
+	store, err := store.NewStore(rc)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to init DB")
+	}
+	defer store.Close(ctx)
+	svc, err := app.NewService(rc, store)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to init service")
+	}
+
+	report, err := svc.CollectGarbage(ctx, *dryRun)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to collect garbage")
+	}
+	log.Info().
+		Bool("dryRun", report.DryRun).
+		Int("versions", len(report.Versions)).
+		Int("pages", len(report.Pages)).
+		Int("themes", len(report.Themes)).
+		Int("blobs", len(report.Blobs)).
+		Int("orphans", len(report.Orphans)).
+		Msg("garbage collection complete")
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal().Err(err).Msg("failed to write report")
+	}
+}
@@ -3,6 +3,7 @@ module github.com/aarongodin/pagebin
 go 1.22.5
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/aymerick/raymond v2.0.2+incompatible
 	github.com/caarlos0/env/v7 v7.1.0
 	github.com/deckarep/golang-set/v2 v2.6.0
@@ -12,7 +13,9 @@ require (
 	github.com/oklog/ulid/v2 v2.1.0
 	github.com/rs/zerolog v1.33.0
 	github.com/stretchr/testify v1.8.1
+	github.com/yuin/goldmark v1.8.6
 	go.etcd.io/bbolt v1.3.11
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -30,5 +33,4 @@ require (
 	github.com/valyala/tcplisten v1.0.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/aarongodin/pagebin/pkg/app"
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/store"
+
+	"github.com/rs/zerolog/log"
+)
+
+// importPages writes the Markdown and HTML files of a directory into the next version and prints the report. Files are
+// matched to pages by path, so running it again only updates the pages whose files changed. The database file is
+// locked while the server is running, so stop the server before importing.
+func importPages(ctx context.Context, rc *config.RuntimeConfig, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	prefix := flags.String("prefix", "", "path prepended to the paths derived from file names")
+	templateName := flags.String("template", "default", "template of pages that do not set one in their front matter")
+	batchSize := flags.Int("batch-size", 100, "number of pages written in each transaction")
+	flags.Usage = func() {
+		os.Stderr.WriteString("usage: pagebin import [flags] <dir>\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	dir := flags.Arg(0)
+
+	store, err := store.NewStore(rc)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to init DB")
+	}
+	defer store.Close(ctx)
+	svc, err := app.NewService(rc, store)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to init service")
+	}
+	if err := app.Provision(ctx, store); err != nil {
+		log.Fatal().Err(err).Msg("failed to provision app")
+	}
+	load(ctx, svc)
+
+	report, err := svc.Import(ctx, os.DirFS(dir), app.ImportOptions{
+		Prefix:       *prefix,
+		TemplateName: *templateName,
+		BatchSize:    *batchSize,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Int("created", len(report.Created)).
+			Int("updated", len(report.Updated)).
+			Msg("failed to import")
+		store.Close(ctx)
+		os.Exit(1)
+	}
+	log.Info().
+		Str("version", report.Version.String()).
+		Str("dir", dir).
+		Int("created", len(report.Created)).
+		Int("updated", len(report.Updated)).
+		Int("skipped", len(report.Skipped)).
+		Msg("import complete")
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal().Err(err).Msg("failed to write report")
+	}
+}
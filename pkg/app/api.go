@@ -1,11 +1,19 @@
 package app
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/aarongodin/pagebin/pkg/core"
 	"github.com/gofiber/fiber/v2"
+	"github.com/joomcode/errorx"
 	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
 )
 
 func NewAdminAPI(service Service) *adminAPI {
@@ -26,9 +34,37 @@ func (api adminAPI) Register(app *fiber.App) {
 	grp.Get("/page/:uid", api.GetPage)
 	grp.Put("/pages", api.PutPage)
 	grp.Delete("/pages/:uid", api.DeletePage)
+	grp.Get("/search", api.Search)
+
+	grp.Get("/redirects", api.GetRedirects)
+	grp.Post("/redirects", api.CreateRedirect)
+	grp.Delete("/redirects/:uid", api.DeleteRedirect)
+
+	grp.Post("/publish", api.Publish)
 
 	grp.Get("/versions", api.GetVersions)
 	grp.Get("/versions/:uid", api.GetVersion)
+	grp.Post("/versions/:uid/rollback", api.Rollback)
+	grp.Get("/versions/:a/diff/:b", api.DiffVersions)
+
+	grp.Get("/releases", api.GetReleases)
+
+	grp.Get("/drafts", api.GetDrafts)
+	grp.Post("/drafts", api.CreateDraft)
+	grp.Delete("/drafts/:name", api.DeleteDraft)
+	grp.Post("/drafts/:name/merge", api.MergeDraft)
+
+	grp.Get("/schedules", api.GetSchedules)
+	grp.Post("/schedules", api.CreateSchedule)
+	grp.Delete("/schedules/:uid", api.DeleteSchedule)
+
+	grp.Post("/gc", api.CollectGarbage)
+	grp.Get("/export", api.Export)
+	grp.Get("/cache/render", api.GetRenderCacheStats)
+
+	grp.Get("/previews", api.GetPreviews)
+	grp.Post("/previews", api.CreatePreview)
+	grp.Delete("/previews/:uid", api.DeletePreview)
 
 	grp.Get("/theme/:uid", api.GetTheme)
 	grp.Get("/theme/:uid/template/:name", api.GetThemeTemplate)
@@ -48,7 +84,7 @@ func (api adminAPI) GetSite(ctx *fiber.Ctx) error {
 // 	if err != nil {
 // 		return err
 // 	}
-// 	return ctx.JSON(paginated[[]core.Page]{
+// 	return ctx.JSON(paginated[core.Page]{
 // 		Cursor: cursor,
 // 		Items:  pages,
 // 	})
@@ -59,11 +95,325 @@ func (api adminAPI) GetPage(ctx *fiber.Ctx) error {
 }
 
 func (api adminAPI) GetVersions(ctx *fiber.Ctx) error {
-	return ctx.SendStatus(http.StatusNotImplemented)
+	cursor, err := getCursorQuery(ctx)
+	if err != nil {
+		return err
+	}
+	versions, next, err := api.service.GetVersions(ctx.Context(), cursor)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(paginated[core.Version]{
+		Cursor: next,
+		Items:  versions,
+	})
 }
 
 func (api adminAPI) GetVersion(ctx *fiber.Ctx) error {
-	return ctx.SendStatus(http.StatusNotImplemented)
+	uid, err := getVersionParam(ctx, api.service, "uid")
+	if err != nil {
+		return err
+	}
+	version, err := api.service.GetVersion(ctx.Context(), uid)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(version)
+}
+
+func (api adminAPI) GetRedirects(ctx *fiber.Ctx) error {
+	target, err := getTargetVersion(ctx, api.service, true)
+	if err != nil {
+		return err
+	}
+	redirects, err := api.service.GetRedirects(ctx.Context(), target)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(redirects)
+}
+
+func (api adminAPI) CreateRedirect(ctx *fiber.Ctx) error {
+	b := core.Redirect{}
+	if err := ctx.BodyParser(&b); err != nil {
+		return err
+	}
+	target, err := getTargetVersion(ctx, api.service, true)
+	if err != nil {
+		return err
+	}
+	redirect, err := api.service.CreateRedirect(ctx.Context(), target, b)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(http.StatusCreated).JSON(redirect)
+}
+
+func (api adminAPI) DeleteRedirect(ctx *fiber.Ctx) error {
+	uid, err := getUIDParam(ctx, "uid")
+	if err != nil {
+		return err
+	}
+	target, err := getTargetVersion(ctx, api.service, true)
+	if err != nil {
+		return err
+	}
+	if err := api.service.DeleteRedirect(ctx.Context(), target, uid); err != nil {
+		return err
+	}
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (api adminAPI) Publish(ctx *fiber.Ctx) error {
+	b := publishBody{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&b); err != nil {
+			return err
+		}
+	}
+	site, err := api.service.Publish(ctx.Context(), b.PublishedBy, b.Message)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(site)
+}
+
+func (api adminAPI) DiffVersions(ctx *fiber.Ctx) error {
+	a, err := getVersionParam(ctx, api.service, "a")
+	if err != nil {
+		return err
+	}
+	b, err := getVersionParam(ctx, api.service, "b")
+	if err != nil {
+		return err
+	}
+	diff, err := api.service.DiffVersions(ctx.Context(), a, b)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(diff)
+}
+
+func (api adminAPI) Rollback(ctx *fiber.Ctx) error {
+	uid, err := getUIDParam(ctx, "uid")
+	if err != nil {
+		return err
+	}
+	b := publishBody{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&b); err != nil {
+			return err
+		}
+	}
+	site, err := api.service.Rollback(ctx.Context(), uid, b.PublishedBy)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(site)
+}
+
+func (api adminAPI) GetDrafts(ctx *fiber.Ctx) error {
+	site, err := api.service.GetSite(ctx.Context())
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(site.Drafts)
+}
+
+func (api adminAPI) CreateDraft(ctx *fiber.Ctx) error {
+	b := draftBody{}
+	if err := ctx.BodyParser(&b); err != nil {
+		return err
+	}
+	site, err := api.service.CreateDraft(ctx.Context(), b.Name, b.CreatedBy)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(http.StatusCreated).JSON(site)
+}
+
+func (api adminAPI) DeleteDraft(ctx *fiber.Ctx) error {
+	if _, err := api.service.DeleteDraft(ctx.Context(), ctx.Params("name")); err != nil {
+		return err
+	}
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (api adminAPI) MergeDraft(ctx *fiber.Ctx) error {
+	b := publishBody{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&b); err != nil {
+			return err
+		}
+	}
+	site, err := api.service.MergeDraft(ctx.Context(), ctx.Params("name"), b.PublishedBy, b.Message)
+	if err != nil {
+		if conflict, ok := errorx.ExtractProperty(err, PropertyMergeConflict); ok {
+			return ctx.Status(http.StatusConflict).JSON(conflict)
+		}
+		return err
+	}
+	return ctx.JSON(site)
+}
+
+func (api adminAPI) GetReleases(ctx *fiber.Ctx) error {
+	cursor, err := getCursorQuery(ctx)
+	if err != nil {
+		return err
+	}
+	releases, next, err := api.service.GetReleases(ctx.Context(), cursor)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(paginated[core.Release]{
+		Cursor: next,
+		Items:  releases,
+	})
+}
+
+func (api adminAPI) GetSchedules(ctx *fiber.Ctx) error {
+	cursor, err := getCursorQuery(ctx)
+	if err != nil {
+		return err
+	}
+	schedules, next, err := api.service.GetSchedules(ctx.Context(), cursor)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(paginated[core.Schedule]{
+		Cursor: next,
+		Items:  schedules,
+	})
+}
+
+func (api adminAPI) CreateSchedule(ctx *fiber.Ctx) error {
+	b := scheduleBody{}
+	if err := ctx.BodyParser(&b); err != nil {
+		return err
+	}
+	schedule, err := api.service.CreateSchedule(ctx.Context(), b.RunAt, b.CreatedBy, b.Message)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(http.StatusCreated).JSON(schedule)
+}
+
+func (api adminAPI) DeleteSchedule(ctx *fiber.Ctx) error {
+	uid, err := getUIDParam(ctx, "uid")
+	if err != nil {
+		return err
+	}
+	if err := api.service.DeleteSchedule(ctx.Context(), uid); err != nil {
+		return err
+	}
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (api adminAPI) CollectGarbage(ctx *fiber.Ctx) error {
+	report, err := api.service.CollectGarbage(ctx.Context(), ctx.QueryBool("dryRun"))
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(report)
+}
+
+// Export responds with a gzipped tarball of the static files of the version given by the X-Pagebin-Version header, or
+// the current version. The tarball is streamed as the files are rendered, so the report of the export, including the
+// paths that failed to render, is the pagebin-export.json file at the end of the tarball. An export that fails while
+// streaming ends the response without closing the tarball.
+func (api adminAPI) Export(ctx *fiber.Ctx) error {
+	site, err := api.service.GetSite(ctx.Context())
+	if err != nil {
+		return err
+	}
+	target := core.NewCurrentTargetVersion(site.Version)
+	if v := strings.TrimSpace(ctx.Get(HeaderPagebinVersion)); v != "" {
+		if target, err = resolveTargetVersion(ctx.Context(), api.service, site, v); err != nil {
+			return err
+		}
+	}
+	options := ExportOptions{
+		Version: target.UID().String(),
+		BaseURL: ctx.BaseURL(),
+	}
+	ctx.Set(fiber.HeaderContentType, "application/gzip")
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="pagebin-`+target.UID().String()+`.tar.gz"`)
+	// the request context must not be used once the handler returns, which is before the body is written
+	ctx.Context().SetBodyStreamWriter(func(bw *bufio.Writer) {
+		if err := api.writeExport(context.Background(), options, bw); err != nil {
+			log.Error().Err(err).Str("version", options.Version).Msg("failed to stream export")
+		}
+	})
+	return nil
+}
+
+func (api adminAPI) writeExport(ctx context.Context, options ExportOptions, out io.Writer) error {
+	w := NewTarExportWriter(out)
+	report, err := api.service.Export(ctx, options, w)
+	if err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := w.WriteFile(exportReportFile, raw); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (api adminAPI) GetPreviews(ctx *fiber.Ctx) error {
+	cursor, err := getCursorQuery(ctx)
+	if err != nil {
+		return err
+	}
+	previews, next, err := api.service.GetPreviews(ctx.Context(), cursor)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(paginated[core.Preview]{
+		Cursor: next,
+		Items:  previews,
+	})
+}
+
+func (api adminAPI) CreatePreview(ctx *fiber.Ctx) error {
+	b := previewBody{}
+	if err := ctx.BodyParser(&b); err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if b.TTL != "" {
+		parsed, err := time.ParseDuration(b.TTL)
+		if err != nil {
+			return core.ErrInvalidPreview.Wrap(err, "ttl must be a duration such as \"24h\"")
+		}
+		ttl = parsed
+	}
+	if b.Version == "" {
+		b.Version = "next"
+	}
+	preview, err := api.service.CreatePreview(ctx.Context(), b.Version, ttl, b.CreatedBy)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(http.StatusCreated).JSON(preview)
+}
+
+func (api adminAPI) DeletePreview(ctx *fiber.Ctx) error {
+	uid, err := getUIDParam(ctx, "uid")
+	if err != nil {
+		return err
+	}
+	if err := api.service.DeletePreview(ctx.Context(), uid); err != nil {
+		return err
+	}
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (api adminAPI) GetRenderCacheStats(ctx *fiber.Ctx) error {
+	return ctx.JSON(api.service.RenderCache().Stats())
 }
 
 func (api adminAPI) GetTheme(ctx *fiber.Ctx) error {
@@ -79,11 +429,11 @@ func (api adminAPI) GetThemeTemplate(ctx *fiber.Ctx) error {
 }
 
 func (api adminAPI) UpdateSite(ctx *fiber.Ctx) error {
-	b := updateSiteBody{}
+	b := core.SiteUpdate{}
 	if err := ctx.BodyParser(&b); err != nil {
 		return err
 	}
-	site, err := api.service.UpdateSite(ctx.Context(), b.Title)
+	site, err := api.service.UpdateSite(ctx.Context(), b)
 	if err != nil {
 		return err
 	}
@@ -95,7 +445,11 @@ func (api adminAPI) PutPage(ctx *fiber.Ctx) error {
 	if err := ctx.BodyParser(&b); err != nil {
 		return err
 	}
-	page, err := api.service.PutPage(ctx.Context(), b.UID, b.Page, []byte(b.Content))
+	target, err := getTargetVersion(ctx, api.service, true)
+	if err != nil {
+		return err
+	}
+	page, err := api.service.PutPage(ctx.Context(), target, b.UID, b.Page, []byte(b.Content))
 	if err != nil {
 		return err
 	}
@@ -107,12 +461,30 @@ func (api adminAPI) DeletePage(ctx *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
-	if err := api.service.DeletePage(ctx.Context(), uid); err != nil {
+	target, err := getTargetVersion(ctx, api.service, true)
+	if err != nil {
+		return err
+	}
+	if err := api.service.DeletePage(ctx.Context(), target, uid); err != nil {
 		return err
 	}
 	return ctx.SendStatus(http.StatusNoContent)
 }
 
+// Search ranks the pages of the target version that match the q query parameter. Unlike the search page, it searches
+// the next version unless another version is given, so that editors can find unpublished pages.
+func (api adminAPI) Search(ctx *fiber.Ctx) error {
+	target, err := getTargetVersion(ctx, api.service, true)
+	if err != nil {
+		return err
+	}
+	results, err := api.service.Search(ctx.Context(), target, ctx.Query("q"), ctx.QueryInt("limit"), ctx.QueryInt("page", 1))
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(results)
+}
+
 func (api adminAPI) PutThemeTemplate(ctx *fiber.Ctx) error {
 	return ctx.SendStatus(http.StatusNotImplemented)
 }
@@ -129,17 +501,35 @@ func (api adminAPI) DeleteThemeAsset(ctx *fiber.Ctx) error {
 	return ctx.SendStatus(http.StatusNotImplemented)
 }
 
-type updateSiteBody struct {
-	Title string `json:"title"`
-}
-
 type pageBody struct {
 	UID     *ulid.ULID
 	Page    core.WritablePage `json:"page"`
 	Content string            `json:"content"`
 }
 
-type paginated[T []core.Page] struct {
+type publishBody struct {
+	PublishedBy string `json:"publishedBy"`
+	Message     string `json:"message"`
+}
+
+type draftBody struct {
+	Name      string `json:"name"`
+	CreatedBy string `json:"createdBy"`
+}
+
+type scheduleBody struct {
+	RunAt     time.Time `json:"runAt"`
+	CreatedBy string    `json:"createdBy"`
+	Message   string    `json:"message"`
+}
+
+type previewBody struct {
+	Version   string `json:"version"`
+	TTL       string `json:"ttl"`
+	CreatedBy string `json:"createdBy"`
+}
+
+type paginated[T any] struct {
 	Cursor *ulid.ULID `json:"cursor"`
-	Items  T          `json:"items"`
+	Items  []T        `json:"items"`
 }
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionsPageSize is the number of versions the version store returns per page.
+const versionsPageSize = 50
+
+// getJSON requests path from app and decodes the response into v when it succeeds.
+func getJSON(t *testing.T, app *fiber.App, path string, v any) int {
+	t.Helper()
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	require.NoError(t, err)
+	if res.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(res.Body).Decode(v))
+	}
+	return res.StatusCode
+}
+
+func TestGetVersionsAPI(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	app := newApp(s.rc, s, newErrorHandler(s))
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	current, err := s.GetVersion(ctx, site.Version)
+	require.NoError(t, err)
+	for i := 0; i < versionsPageSize; i++ {
+		_, err := s.store.Versions().CreateVersion(ctx, current.Pages, current.Theme, "admin")
+		require.NoError(t, err)
+	}
+	all, err := s.store.Versions().GetAllVersions(ctx)
+	require.NoError(t, err)
+
+	var first paginated[core.Version]
+	require.Equal(t, http.StatusOK, getJSON(t, app, "/api/versions", &first))
+	assert.Len(t, first.Items, versionsPageSize)
+	require.NotNil(t, first.Cursor)
+	for i := 1; i < len(first.Items); i++ {
+		assert.Greater(t, first.Items[i-1].UID.Compare(first.Items[i].UID), 0, "versions are listed newest first")
+	}
+
+	var last paginated[core.Version]
+	require.Equal(t, http.StatusOK, getJSON(t, app, "/api/versions?cursor="+first.Cursor.String(), &last))
+	assert.Nil(t, last.Cursor, "the last page has no cursor")
+	assert.Len(t, last.Items, len(all)-versionsPageSize)
+	assert.Equal(t, *first.Cursor, last.Items[0].UID)
+	assert.Less(t, last.Items[0].UID.Compare(first.Items[len(first.Items)-1].UID), 0, "pages do not overlap")
+
+	var invalid paginated[core.Version]
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, app, "/api/versions?cursor=nope", &invalid))
+}
+
+func TestGetVersionAPIMetadata(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	app := newApp(s.rc, s, newErrorHandler(s))
+	before := time.Now().Add(-time.Second)
+	first, err := s.Publish(ctx, "alice", "first")
+	require.NoError(t, err)
+	_, err = s.Publish(ctx, "bob", "second")
+	require.NoError(t, err)
+
+	var version core.Version
+	require.Equal(t, http.StatusOK, getJSON(t, app, "/api/versions/"+first.Version.String(), &version))
+	assert.Equal(t, "alice", version.PublishedBy)
+	assert.Equal(t, "first", version.Message)
+	assert.True(t, version.PublishedAt.After(before))
+	assert.False(t, version.CreatedAt.IsZero())
+	publishedAt := version.PublishedAt
+
+	_, err = s.Rollback(ctx, first.Version, "carol")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, getJSON(t, app, "/api/versions/current", &version))
+	assert.Equal(t, first.Version, version.UID)
+	assert.Equal(t, "carol", version.PublishedBy, "a rollback records who published the version again")
+	assert.Equal(t, "first", version.Message, "a rollback keeps the message")
+	assert.False(t, version.PublishedAt.Before(publishedAt))
+
+	var next core.Version
+	require.Equal(t, http.StatusOK, getJSON(t, app, "/api/versions/next", &next))
+	assert.Equal(t, "bob", next.CreatedBy, "the next version is created by whoever published its base")
+	assert.False(t, next.CreatedAt.IsZero())
+	assert.True(t, next.PublishedAt.IsZero())
+
+	assert.Equal(t, http.StatusNotFound, getJSON(t, app, "/api/versions/"+ulid.Make().String(), &version))
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+)
+
+const (
+	assetMarkerCSS = "<!-- pagebin:assets:css -->"
+	assetMarkerJS  = "<!-- pagebin:assets:js -->"
+	// assetHashLength is the number of hex characters of the content hash used in asset file names.
+	assetHashLength = 16
+
+	contentTypeCSS = "text/css; charset=utf-8"
+	contentTypeJS  = "text/javascript; charset=utf-8"
+)
+
+type compiledAsset struct {
+	contentType string
+	body        []byte
+}
+
+// compiledAssets are the CSS and JS files of a theme, named by a hash of their content so that they can be cached
+// forever. The file names are listed in the order the theme declares its assets.
+type compiledAssets struct {
+	files map[string]compiledAsset
+	css   []string
+	js    []string
+}
+
+func compileAssets(css [][]byte, js [][]byte, options core.ThemeAssetOptions) *compiledAssets {
+	a := &compiledAssets{
+		files: map[string]compiledAsset{},
+	}
+	a.css = a.add(css, ".css", contentTypeCSS, []byte("\n"), minifyCSS, options)
+	a.js = a.add(js, ".js", contentTypeJS, []byte("\n;\n"), minifyJS, options)
+	return a
+}
+
+func (a *compiledAssets) add(sources [][]byte, ext string, contentType string, sep []byte, minify func([]byte) []byte, options core.ThemeAssetOptions) []string {
+	if options.Concat && len(sources) > 1 {
+		sources = [][]byte{bytes.Join(sources, sep)}
+	}
+	names := make([]string, 0, len(sources))
+	for _, body := range sources {
+		if options.Minify {
+			body = minify(body)
+		}
+		sum := sha256.Sum256(body)
+		name := hex.EncodeToString(sum[:])[:assetHashLength] + ext
+		a.files[name] = compiledAsset{contentType, body}
+		names = append(names, name)
+	}
+	return names
+}
+
+// replaceMarkers swaps the asset markers of a template source for the link and script tags of the theme assets.
+func (a *compiledAssets) replaceMarkers(source string) string {
+	if a == nil {
+		return source
+	}
+	if strings.Contains(source, assetMarkerCSS) {
+		tags := make([]string, len(a.css))
+		for i, name := range a.css {
+			tags[i] = fmt.Sprintf(`<link rel="stylesheet" href="%s/%s">`, pathAssets, name)
+		}
+		source = strings.ReplaceAll(source, assetMarkerCSS, strings.Join(tags, "\n"))
+	}
+	if strings.Contains(source, assetMarkerJS) {
+		tags := make([]string, len(a.js))
+		for i, name := range a.js {
+			tags[i] = fmt.Sprintf(`<script src="%s/%s"></script>`, pathAssets, name)
+		}
+		source = strings.ReplaceAll(source, assetMarkerJS, strings.Join(tags, "\n"))
+	}
+	return source
+}
+
+// minifyCSS removes comments and whitespace that does not separate tokens. Strings are copied unchanged.
+func minifyCSS(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	space := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 3
+			}
+			space = true
+			continue
+		case isCSSSpace(c):
+			space = true
+			continue
+		}
+		if space && len(out) > 0 && !strings.ContainsRune("{};:,>", rune(out[len(out)-1])) && !strings.ContainsRune("{};,>)", rune(c)) {
+			out = append(out, ' ')
+		}
+		space = false
+		if c == '}' && len(out) > 0 && out[len(out)-1] == ';' {
+			out = out[:len(out)-1]
+		}
+		if c == '"' || c == '\'' {
+			end := i + 1
+			for end < len(src) && src[end] != c {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(src))
+			out = append(out, src[i:end]...)
+			i = end - 1
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func isCSSSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// minifyJS is deliberately conservative: it only drops blank lines, lines that are entirely a // comment, and
+// trailing whitespace, which is safe for any script that does not rely on them inside template literals.
+func minifyJS(src []byte) []byte {
+	lines := bytes.Split(src, []byte("\n"))
+	out := make([][]byte, 0, len(lines))
+	for _, line := range lines {
+		line = bytes.TrimRight(line, " \t\r")
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) == 0 || bytes.HasPrefix(trimmed, []byte("//")) {
+			continue
+		}
+		out = append(out, line)
+	}
+	return bytes.Join(out, []byte("\n"))
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileAssets(t *testing.T) {
+	css := [][]byte{[]byte("body { color: red; }"), []byte("p { margin: 0; }")}
+	js := [][]byte{[]byte("console.log(1)")}
+
+	separate := compileAssets(css, js, core.ThemeAssetOptions{})
+	require.Len(t, separate.css, 2)
+	require.Len(t, separate.js, 1)
+	assert.Regexp(t, `^[0-9a-f]{16}\.css$`, separate.css[0])
+	assert.Equal(t, css[1], separate.files[separate.css[1]].body)
+	assert.Equal(t, contentTypeJS, separate.files[separate.js[0]].contentType)
+
+	concat := compileAssets(css, js, core.ThemeAssetOptions{Concat: true, Minify: true})
+	require.Len(t, concat.css, 1)
+	assert.Equal(t, "body{color:red}p{margin:0}", string(concat.files[concat.css[0]].body))
+	assert.NotEqual(t, separate.css[0], concat.css[0])
+}
+
+func TestCompileThemeAssetMarkers(t *testing.T) {
+	assets := compileAssets([][]byte{[]byte("a{}")}, [][]byte{[]byte("x()")}, core.ThemeAssetOptions{})
+	c, err := compileTheme(ulid.Make(), map[string]string{
+		"default": "<head>" + assetMarkerCSS + "</head>" + assetMarkerJS,
+	}, assets)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`<head><link rel="stylesheet" href="/_assets/`+assets.css[0]+`"></head><script src="/_assets/`+assets.js[0]+`"></script>`,
+		execTheme(t, c, "default", nil),
+	)
+}
+
+func TestMinifyCSS(t *testing.T) {
+	testCases := map[string]string{
+		"a  b ,  c > d { color: red ; }":               "a b,c>d{color:red}",
+		"/* comment */ a { margin: 0 auto; }":          "a{margin:0 auto}",
+		`a::before { content: "  /* kept */  "; }`:     `a::before{content:"  /* kept */  "}`,
+		"@media (min-width: 600px) {\n  a { b: c }\n}": "@media (min-width:600px){a{b:c}}",
+		"a { width: calc(100% - 2px); }":               "a{width:calc(100% - 2px)}",
+	}
+	for src, expected := range testCases {
+		assert.Equal(t, expected, string(minifyCSS([]byte(src))), src)
+	}
+}
+
+func TestMinifyJS(t *testing.T) {
+	src := "// header\nfunction f() {\n\n  return 1; \n  // note\n}\n"
+	assert.Equal(t, "function f() {\n  return 1;\n}", string(minifyJS([]byte(src))))
+}
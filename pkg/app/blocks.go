@@ -0,0 +1,213 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/aymerick/raymond"
+)
+
+// blockTemplatePrefix names the theme templates that override how a block type renders, such as blocks/heading. Custom
+// blocks are rendered by blocks/custom/<name> and have no built-in template.
+const blockTemplatePrefix = "blocks/"
+
+const maxHeadingLevel = 6
+
+var customBlockName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// builtinBlockTemplates render each block type when the theme does not override it. Text fields may contain inline
+// HTML and are not escaped, the same as HTML page content.
+var builtinBlockTemplates = map[string]*raymond.Template{
+	core.BlockTypeParagraph: raymond.MustParse(`<p{{#if id}} id="{{id}}"{{/if}}>{{{text}}}</p>`),
+	core.BlockTypeHeading:   raymond.MustParse(`<h{{level}}{{#if id}} id="{{id}}"{{/if}}>{{{text}}}</h{{level}}>`),
+	core.BlockTypeImage: raymond.MustParse(`<figure{{#if id}} id="{{id}}"{{/if}}><img src="{{url}}" alt="{{alt}}">` +
+		`{{#if caption}}<figcaption>{{{caption}}}</figcaption>{{/if}}</figure>`),
+	core.BlockTypeList: raymond.MustParse(`{{#if ordered}}<ol{{#if id}} id="{{id}}"{{/if}}>{{else}}<ul{{#if id}} id="{{id}}"{{/if}}>{{/if}}` +
+		`{{#each items}}<li>{{{this}}}</li>{{/each}}{{#if ordered}}</ol>{{else}}</ul>{{/if}}`),
+	core.BlockTypeQuote: raymond.MustParse(`<blockquote{{#if id}} id="{{id}}"{{/if}}><p>{{{text}}}</p>` +
+		`{{#if cite}}<cite>{{cite}}</cite>{{/if}}</blockquote>`),
+	core.BlockTypeCode: raymond.MustParse(`<pre{{#if id}} id="{{id}}"{{/if}}><code{{#if language}} class="language-{{language}}"{{/if}}>` +
+		`{{code}}</code></pre>`),
+	core.BlockTypeEmbed: raymond.MustParse(`<figure class="embed"{{#if id}} id="{{id}}"{{/if}}>` +
+		`<iframe src="{{url}}" loading="lazy" allowfullscreen></iframe>{{#if caption}}<figcaption>{{{caption}}}</figcaption>{{/if}}</figure>`),
+	core.BlockTypeColumns: raymond.MustParse(`<div class="columns"{{#if id}} id="{{id}}"{{/if}}>` +
+		`{{#each columns}}<div class="column">{{{this}}}</div>{{/each}}</div>`),
+}
+
+// parseBlockDocument decodes and validates a block document.
+func parseBlockDocument(content []byte) (core.BlockDocument, error) {
+	var doc core.BlockDocument
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return doc, core.ErrInvalidContent.Wrap(err, "block document is not valid")
+	}
+	if doc.Version != core.BlockDocumentVersion {
+		return doc, core.ErrInvalidContent.New("block document version %d is not supported", doc.Version)
+	}
+	return doc, validateBlocks(doc.Blocks, "blocks")
+}
+
+func validateBlocks(blocks []core.Block, at string) error {
+	for i, block := range blocks {
+		if err := validateBlock(block, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateBlock(block core.Block, at string) error {
+	if block.Type != core.BlockTypeColumns && len(block.Columns) > 0 {
+		return core.ErrInvalidContent.New("%s: only columns blocks may have columns", at)
+	}
+	switch block.Type {
+	case core.BlockTypeParagraph:
+		return validateBlockFields(block, at, []string{"text"}, nil)
+	case core.BlockTypeHeading:
+		if err := validateBlockFields(block, at, []string{"text"}, nil); err != nil {
+			return err
+		}
+		level, ok := block.Data["level"].(float64)
+		if !ok || level != float64(int(level)) || level < 1 || level > maxHeadingLevel {
+			return core.ErrInvalidContent.New("%s.data.level must be a number from 1 to %d", at, maxHeadingLevel)
+		}
+	case core.BlockTypeImage:
+		if err := validateBlockFields(block, at, []string{"url"}, []string{"alt", "caption"}); err != nil {
+			return err
+		}
+		return validateBlockURL(block, at, true)
+	case core.BlockTypeList:
+		if err := validateBlockFields(block, at, nil, []string{"style"}); err != nil {
+			return err
+		}
+		if style, ok := block.Data["style"]; ok && style != "ordered" && style != "unordered" {
+			return core.ErrInvalidContent.New("%s.data.style must be \"ordered\" or \"unordered\"", at)
+		}
+		items, ok := block.Data["items"].([]any)
+		if !ok {
+			return core.ErrInvalidContent.New("%s.data.items must be a list", at)
+		}
+		for j, item := range items {
+			if _, ok := item.(string); !ok {
+				return core.ErrInvalidContent.New("%s.data.items[%d] must be a string", at, j)
+			}
+		}
+	case core.BlockTypeQuote:
+		return validateBlockFields(block, at, []string{"text"}, []string{"cite"})
+	case core.BlockTypeCode:
+		return validateBlockFields(block, at, []string{"code"}, []string{"language"})
+	case core.BlockTypeEmbed:
+		if err := validateBlockFields(block, at, []string{"url"}, []string{"caption"}); err != nil {
+			return err
+		}
+		return validateBlockURL(block, at, false)
+	case core.BlockTypeColumns:
+		if len(block.Columns) == 0 {
+			return core.ErrInvalidContent.New("%s.columns must have at least one column", at)
+		}
+		for j, column := range block.Columns {
+			if err := validateBlocks(column, fmt.Sprintf("%s.columns[%d]", at, j)); err != nil {
+				return err
+			}
+		}
+	case core.BlockTypeCustom:
+		name, ok := block.Data["name"].(string)
+		if !ok || !customBlockName.MatchString(name) {
+			return core.ErrInvalidContent.New("%s.data.name must be lowercase letters, digits and dashes", at)
+		}
+	default:
+		return core.ErrInvalidContent.New("%s: unknown block type \"%s\"", at, block.Type)
+	}
+	return nil
+}
+
+// validateBlockFields checks that the required fields are strings, and that the optional fields are strings when set.
+func validateBlockFields(block core.Block, at string, required []string, optional []string) error {
+	for _, field := range required {
+		if _, ok := block.Data[field].(string); !ok {
+			return core.ErrInvalidContent.New("%s.data.%s is required and must be a string", at, field)
+		}
+	}
+	for _, field := range optional {
+		if value, exists := block.Data[field]; exists {
+			if _, ok := value.(string); !ok {
+				return core.ErrInvalidContent.New("%s.data.%s must be a string", at, field)
+			}
+		}
+	}
+	return nil
+}
+
+// validateBlockURL checks that the url field is an http or https URL, or a path on the site when relative is set.
+func validateBlockURL(block core.Block, at string, relative bool) error {
+	raw := block.Data["url"].(string)
+	u, err := url.Parse(raw)
+	if err == nil {
+		if u.Scheme == "http" || u.Scheme == "https" {
+			return nil
+		}
+		if relative && u.Scheme == "" && u.Host == "" && strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//") {
+			return nil
+		}
+	}
+	return core.ErrInvalidContent.New("%s.data.url \"%s\" is not an allowed URL", at, raw)
+}
+
+// renderBlocks renders blocks to HTML with the block templates of the theme, falling back to the built-in templates.
+func (c *compiledTheme) renderBlocks(blocks []core.Block) (string, error) {
+	var out strings.Builder
+	for _, block := range blocks {
+		html, err := c.renderBlock(block)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(html)
+		out.WriteByte('\n')
+	}
+	return out.String(), nil
+}
+
+func (c *compiledTheme) renderBlock(block core.Block) (string, error) {
+	data := make(map[string]any, len(block.Data)+4)
+	for k, v := range block.Data {
+		data[k] = v
+	}
+	data["type"] = block.Type
+	data["id"] = block.ID
+	templateName := blockTemplatePrefix + block.Type
+	switch block.Type {
+	case core.BlockTypeList:
+		data["ordered"] = block.Data["style"] == "ordered"
+	case core.BlockTypeColumns:
+		columns := make([]raymond.SafeString, len(block.Columns))
+		for i, column := range block.Columns {
+			html, err := c.renderBlocks(column)
+			if err != nil {
+				return "", err
+			}
+			columns[i] = raymond.SafeString(html)
+		}
+		data["columns"] = columns
+	case core.BlockTypeCustom:
+		templateName = blockTemplatePrefix + "custom/" + raymond.Str(block.Data["name"])
+	}
+
+	tpl, ok := c.templates[templateName]
+	if !ok {
+		tpl, ok = builtinBlockTemplates[block.Type]
+	}
+	if !ok {
+		return fmt.Sprintf("<!-- no template %s -->", templateName), nil
+	}
+	html, err := tpl.Exec(data)
+	if err != nil {
+		return "", core.ErrThemeTemplateExec.Wrap(err, "template \"%s\" failed to execute", templateName)
+	}
+	return html, nil
+}
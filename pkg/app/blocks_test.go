@@ -0,0 +1,90 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBlockDocument = `{
+	"version": 1,
+	"blocks": [
+		{"type": "heading", "id": "intro", "data": {"text": "Hello", "level": 2}},
+		{"type": "paragraph", "data": {"text": "Some <em>text</em>"}},
+		{"type": "image", "data": {"url": "/img/a.png", "alt": "A \"quote\""}},
+		{"type": "list", "data": {"style": "ordered", "items": ["one", "two"]}},
+		{"type": "quote", "data": {"text": "Quoted", "cite": "Someone"}},
+		{"type": "code", "data": {"code": "<b>x</b>", "language": "html"}},
+		{"type": "embed", "data": {"url": "https://example.com/video"}},
+		{"type": "columns", "columns": [
+			[{"type": "paragraph", "data": {"text": "left"}}],
+			[{"type": "paragraph", "data": {"text": "right"}}]
+		]},
+		{"type": "custom", "data": {"name": "callout", "body": "Careful"}}
+	]
+}`
+
+func TestParseBlockDocument(t *testing.T) {
+	doc, err := parseBlockDocument([]byte(testBlockDocument))
+	require.NoError(t, err)
+	assert.Len(t, doc.Blocks, 9)
+
+	invalid := map[string]string{
+		"not json":              `{`,
+		"unknown field":         `{"version": 1, "blocks": [], "extra": true}`,
+		"unsupported version":   `{"version": 2, "blocks": []}`,
+		"unknown type":          `{"version": 1, "blocks": [{"type": "table"}]}`,
+		"missing text":          `{"version": 1, "blocks": [{"type": "paragraph", "data": {}}]}`,
+		"heading level":         `{"version": 1, "blocks": [{"type": "heading", "data": {"text": "a", "level": 7}}]}`,
+		"list items":            `{"version": 1, "blocks": [{"type": "list", "data": {"items": [1]}}]}`,
+		"list style":            `{"version": 1, "blocks": [{"type": "list", "data": {"style": "dotted", "items": []}}]}`,
+		"image url":             `{"version": 1, "blocks": [{"type": "image", "data": {"url": "javascript:alert(1)"}}]}`,
+		"embed relative url":    `{"version": 1, "blocks": [{"type": "embed", "data": {"url": "/video"}}]}`,
+		"empty columns":         `{"version": 1, "blocks": [{"type": "columns"}]}`,
+		"nested invalid":        `{"version": 1, "blocks": [{"type": "columns", "columns": [[{"type": "nope"}]]}]}`,
+		"columns on paragraph":  `{"version": 1, "blocks": [{"type": "paragraph", "data": {"text": "a"}, "columns": [[]]}]}`,
+		"custom name":           `{"version": 1, "blocks": [{"type": "custom", "data": {"name": "../x"}}]}`,
+		"optional not a string": `{"version": 1, "blocks": [{"type": "quote", "data": {"text": "a", "cite": 1}}]}`,
+	}
+	for name, content := range invalid {
+		_, err := parseBlockDocument([]byte(content))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRenderBlocks(t *testing.T) {
+	doc, err := parseBlockDocument([]byte(testBlockDocument))
+	require.NoError(t, err)
+
+	c, err := compileTheme(ulid.Make(), map[string]string{}, nil)
+	require.NoError(t, err)
+	out, err := c.renderBlocks(doc.Blocks)
+	require.NoError(t, err)
+	assert.Equal(t, `<h2 id="intro">Hello</h2>
+<p>Some <em>text</em></p>
+<figure><img src="/img/a.png" alt="A &quot;quote&quot;"></figure>
+<ol><li>one</li><li>two</li></ol>
+<blockquote><p>Quoted</p><cite>Someone</cite></blockquote>
+<pre><code class="language-html">&lt;b&gt;x&lt;/b&gt;</code></pre>
+<figure class="embed"><iframe src="https://example.com/video" loading="lazy" allowfullscreen></iframe></figure>
+<div class="columns"><div class="column"><p>left</p>
+</div><div class="column"><p>right</p>
+</div></div>
+<!-- no template blocks/custom/callout -->
+`, out)
+
+	themed, err := compileTheme(ulid.Make(), map[string]string{
+		"blocks/heading":        `<h{{level}} class="title">{{text}}</h{{level}}>`,
+		"blocks/custom/callout": `<aside>{{> icon}}{{body}}</aside>`,
+		"partials/icon":         `<i></i>`,
+	}, nil)
+	require.NoError(t, err)
+	out, err = themed.renderBlocks(doc.Blocks[:1])
+	require.NoError(t, err)
+	assert.Equal(t, "<h2 class=\"title\">Hello</h2>\n", out)
+	out, err = themed.renderBlocks(doc.Blocks[8:])
+	require.NoError(t, err)
+	assert.Equal(t, "<aside><i></i>Careful</aside>\n", out)
+}
@@ -0,0 +1,313 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/aarongodin/pagebin/pkg/store"
+	"github.com/aymerick/raymond"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	// dataPageQuerier is the data frame key of the pageQuerier that the pages helper runs queries with.
+	dataPageQuerier = "_pageQuerier"
+	// collectionCacheVersions is the number of versions whose pages are kept for queries.
+	collectionCacheVersions = 16
+	// maxCachedQueries is the number of query results kept for each version.
+	maxCachedQueries = 256
+)
+
+const (
+	SortDate    = "date"
+	SortUpdated = "updated"
+	SortTitle   = "title"
+	SortPath    = "path"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// PageQuery selects pages of a version for a template. Pages are sorted by date (when they were created), updated,
+// title or path, and are in descending order by default for date and updated, and ascending order otherwise. A limit
+// of 0 returns every matching page; otherwise Page selects the page of results, starting from 1.
+type PageQuery struct {
+	Tag    string
+	Prefix string
+	Sort   string
+	Order  string
+	Limit  int
+	Page   int
+}
+
+// PageCollection is the result of a PageQuery. Prev and Next are the numbers of the neighbouring pages of results, or
+// 0 when there is none.
+type PageCollection struct {
+	Items []PageContext `handlebars:"items"`
+	Total int           `handlebars:"total"`
+	Page  int           `handlebars:"page"`
+	// PageCount is the number of pages of results. It is not named pages, which is the name of the helper.
+	PageCount int `handlebars:"pageCount"`
+	Prev      int `handlebars:"prev"`
+	Next      int `handlebars:"next"`
+
+	// pages are the pages of Items.
+	pages []core.Page
+}
+
+// Collections answers page queries against the pages of a version. The pages of a version, and the results of queries
+// against them, are cached until the version is purged.
+type Collections interface {
+	Query(ctx context.Context, version ulid.ULID, query PageQuery) (PageCollection, error)
+	// Revision identifies the state of the pages of a version, and changes whenever a page is added, removed or updated.
+	Revision(ctx context.Context, version ulid.ULID) (string, error)
+	PurgeVersion(version ulid.ULID)
+	Purge()
+}
+
+// pageQuerier runs queries for a template against the version being rendered.
+type pageQuerier func(query PageQuery) (PageCollection, error)
+
+type versionPages struct {
+	pages    []core.Page
+	revision string
+	mu       sync.Mutex
+	results  map[PageQuery]PageCollection
+}
+
+type cachedCollections struct {
+	mu       sync.Mutex
+	gen      uint64
+	cache    *lru.Cache[ulid.ULID, *versionPages]
+	versions store.VersionStore
+	pages    store.PageStore
+}
+
+func (c *cachedCollections) Query(ctx context.Context, version ulid.ULID, query PageQuery) (PageCollection, error) {
+	query, err := normalizePageQuery(query)
+	if err != nil {
+		return PageCollection{}, err
+	}
+	vp, err := c.versionPages(ctx, version)
+	if err != nil {
+		return PageCollection{}, err
+	}
+	vp.mu.Lock()
+	defer vp.mu.Unlock()
+	if result, ok := vp.results[query]; ok {
+		return result, nil
+	}
+	result := runPageQuery(vp.pages, query)
+	if len(vp.results) >= maxCachedQueries {
+		clear(vp.results)
+	}
+	vp.results[query] = result
+	return result, nil
+}
+
+func (c *cachedCollections) Revision(ctx context.Context, version ulid.ULID) (string, error) {
+	vp, err := c.versionPages(ctx, version)
+	if err != nil {
+		return "", err
+	}
+	return vp.revision, nil
+}
+
+func (c *cachedCollections) PurgeVersion(version ulid.ULID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.cache.Remove(version)
+}
+
+func (c *cachedCollections) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.cache.Purge()
+}
+
+// versionPages returns the pages of a version that have a path without params, sorted by path. A version purged while
+// its pages load is not cached, since the loaded pages may be out of date.
+func (c *cachedCollections) versionPages(ctx context.Context, uid ulid.ULID) (*versionPages, error) {
+	c.mu.Lock()
+	vp, ok := c.cache.Get(uid)
+	gen := c.gen
+	c.mu.Unlock()
+	if ok {
+		return vp, nil
+	}
+
+	version, err := c.versions.GetVersion(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	vp = &versionPages{results: map[PageQuery]PageCollection{}}
+	h := sha256.New()
+	for path, pageUID := range version.Pages {
+		if strings.ContainsAny(path, string(routeParamPrefix)+string(routeWildcardPrefix)) {
+			continue
+		}
+		page, err := c.pages.GetPage(ctx, pageUID)
+		if err != nil {
+			return nil, err
+		}
+		vp.pages = append(vp.pages, page)
+	}
+	slices.SortFunc(vp.pages, func(a, b core.Page) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	for _, page := range vp.pages {
+		h.Write(page.UID[:])
+		h.Write([]byte(page.Path))
+		h.Write([]byte(page.UpdatedAt.String()))
+	}
+	vp.revision = hex.EncodeToString(h.Sum(nil)[:16])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen == c.gen {
+		c.cache.Add(uid, vp)
+	}
+	return vp, nil
+}
+
+// normalizePageQuery fills the default sort, order and page of a query so that equal queries share a cache entry.
+func normalizePageQuery(query PageQuery) (PageQuery, error) {
+	switch query.Sort {
+	case "":
+		query.Sort = SortDate
+	case SortDate, SortUpdated, SortTitle, SortPath:
+	default:
+		return query, core.ErrInvalidPageQuery.New("unknown sort \"%s\"", query.Sort)
+	}
+	switch query.Order {
+	case "":
+		query.Order = OrderAsc
+		if query.Sort == SortDate || query.Sort == SortUpdated {
+			query.Order = OrderDesc
+		}
+	case OrderAsc, OrderDesc:
+	default:
+		return query, core.ErrInvalidPageQuery.New("unknown order \"%s\"", query.Order)
+	}
+	if query.Limit < 0 {
+		return query, core.ErrInvalidPageQuery.New("limit must not be negative")
+	}
+	if query.Limit == 0 || query.Page < 1 {
+		query.Page = 1
+	}
+	return query, nil
+}
+
+func runPageQuery(pages []core.Page, query PageQuery) PageCollection {
+	matched := make([]core.Page, 0, len(pages))
+	for _, page := range pages {
+		if query.Prefix != "" && !strings.HasPrefix(page.Path, query.Prefix) {
+			continue
+		}
+		if query.Tag != "" && !slices.Contains(page.Tags, query.Tag) {
+			continue
+		}
+		matched = append(matched, page)
+	}
+	slices.SortStableFunc(matched, func(a, b core.Page) int {
+		var n int
+		switch query.Sort {
+		case SortDate:
+			n = a.CreatedAt.Compare(b.CreatedAt)
+		case SortUpdated:
+			n = a.UpdatedAt.Compare(b.UpdatedAt)
+		case SortTitle:
+			n = cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		}
+		if n == 0 {
+			n = strings.Compare(a.Path, b.Path)
+		}
+		if query.Order == OrderDesc {
+			return -n
+		}
+		return n
+	})
+
+	collection := PageCollection{Total: len(matched), Page: query.Page, PageCount: 1}
+	if query.Limit > 0 {
+		collection.PageCount = max(1, (len(matched)+query.Limit-1)/query.Limit)
+		start := min((query.Page-1)*query.Limit, len(matched))
+		matched = matched[start:min(start+query.Limit, len(matched))]
+	}
+	if query.Page > 1 && query.Page <= collection.PageCount {
+		collection.Prev = query.Page - 1
+	}
+	if query.Page < collection.PageCount {
+		collection.Next = query.Page + 1
+	}
+	collection.pages = matched
+	collection.Items = make([]PageContext, len(matched))
+	for i, page := range matched {
+		collection.Items[i] = newPageContext(page)
+	}
+	return collection
+}
+
+// newPageQuerier binds queries to the version being rendered.
+func newPageQuerier(ctx context.Context, collections Collections, version ulid.ULID) pageQuerier {
+	return func(query PageQuery) (PageCollection, error) {
+		return collections.Query(ctx, version, query)
+	}
+}
+
+// pagesHelper renders its block with the collection of pages matching the tag, prefix, sort, order, limit and page
+// hash arguments, or the else block when no page matches:
+//
+//	{{#pages prefix="/blog/" limit=10 page=request.query.page}}{{#each items}}...{{/each}}{{else}}...{{/pages}}
+func pagesHelper(options *raymond.Options) raymond.SafeString {
+	querier, ok := options.Data(dataPageQuerier).(pageQuerier)
+	if !ok {
+		panic(core.ErrThemeTemplateExec.New("pages can only be used in page templates"))
+	}
+	collection, err := querier(PageQuery{
+		Tag:    options.HashStr("tag"),
+		Prefix: options.HashStr("prefix"),
+		Sort:   options.HashStr("sort"),
+		Order:  options.HashStr("order"),
+		Limit:  hashInt(options, "limit"),
+		Page:   hashInt(options, "page"),
+	})
+	if err != nil {
+		panic(err)
+	}
+	if len(collection.Items) == 0 {
+		return raymond.SafeString(options.Inverse())
+	}
+	return raymond.SafeString(options.FnWith(collection))
+}
+
+// hashInt returns an integer hash argument, which may also be given as a string such as a query parameter. Missing and
+// malformed values are 0.
+func hashInt(options *raymond.Options, name string) int {
+	switch value := options.HashProp(name).(type) {
+	case int:
+		return value
+	case string:
+		n, _ := strconv.Atoi(value)
+		return n
+	}
+	return 0
+}
+
+func NewCollections(versions store.VersionStore, pages store.PageStore) (Collections, error) {
+	cache, err := lru.New[ulid.ULID, *versionPages](collectionCacheVersions)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedCollections{cache: cache, versions: versions, pages: pages}, nil
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCollectionPages() []core.Page {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+	return []core.Page{
+		{UID: ulid.Make(), Title: "Home", Path: "/", CreatedAt: day(1), UpdatedAt: day(9)},
+		{UID: ulid.Make(), Title: "Beta", Path: "/blog/beta", Tags: []string{"news"}, CreatedAt: day(3), UpdatedAt: day(3)},
+		{UID: ulid.Make(), Title: "alpha", Path: "/blog/alpha", Tags: []string{"news", "go"}, CreatedAt: day(2), UpdatedAt: day(8)},
+		{UID: ulid.Make(), Title: "Gamma", Path: "/blog/gamma", Tags: []string{"go"}, CreatedAt: day(4), UpdatedAt: day(4)},
+	}
+}
+
+func collectionPaths(c PageCollection) []string {
+	paths := make([]string, len(c.Items))
+	for i, item := range c.Items {
+		paths[i] = item.Path
+	}
+	return paths
+}
+
+func TestRunPageQuery(t *testing.T) {
+	pages := testCollectionPages()
+	query := func(q PageQuery) PageCollection {
+		t.Helper()
+		q, err := normalizePageQuery(q)
+		require.NoError(t, err)
+		return runPageQuery(pages, q)
+	}
+
+	assert.Equal(t, []string{"/blog/gamma", "/blog/beta", "/blog/alpha"}, collectionPaths(query(PageQuery{Prefix: "/blog/"})))
+	assert.Equal(t, []string{"/blog/alpha", "/blog/beta"}, collectionPaths(query(PageQuery{Tag: "news", Order: OrderAsc})))
+	assert.Equal(t, []string{"/blog/alpha", "/blog/beta", "/blog/gamma", "/"}, collectionPaths(query(PageQuery{Sort: SortTitle})))
+	assert.Equal(t, []string{"/", "/blog/alpha", "/blog/gamma", "/blog/beta"}, collectionPaths(query(PageQuery{Sort: SortUpdated})))
+
+	first := query(PageQuery{Sort: SortPath, Limit: 3})
+	assert.Equal(t, []string{"/", "/blog/alpha", "/blog/beta"}, collectionPaths(first))
+	assert.Equal(t, []int{4, 1, 2, 0, 2}, []int{first.Total, first.Page, first.PageCount, first.Prev, first.Next})
+	second := query(PageQuery{Sort: SortPath, Limit: 3, Page: 2})
+	assert.Equal(t, []string{"/blog/gamma"}, collectionPaths(second))
+	assert.Equal(t, 1, second.Prev)
+	assert.Equal(t, 0, second.Next)
+	assert.Empty(t, query(PageQuery{Limit: 3, Page: 5}).Items)
+
+	for _, invalid := range []PageQuery{{Sort: "size"}, {Order: "random"}, {Limit: -1}} {
+		_, err := normalizePageQuery(invalid)
+		assert.True(t, core.IsInvalid(err))
+	}
+}
+
+func TestPagesHelper(t *testing.T) {
+	c, err := compileTheme(ulid.Make(), map[string]string{
+		"default": `{{#pages tag=request.query.tag sort="title" limit=2 page=request.query.page}}` +
+			`{{#each items}}<a href="{{path}}">{{title}}</a>{{/each}} {{page}}/{{pageCount}}{{else}}none{{/pages}}`,
+	}, nil)
+	require.NoError(t, err)
+	tm := &themeManager{current: c}
+
+	pages := testCollectionPages()
+	render := func(query map[string]string) string {
+		t.Helper()
+		data := RenderContext{Request: RequestContext{Query: query}}
+		data.pages = func(q PageQuery) (PageCollection, error) {
+			q, err := normalizePageQuery(q)
+			if err != nil {
+				return PageCollection{}, err
+			}
+			return runPageQuery(pages, q), nil
+		}
+		out, err := tm.Render(context.Background(), c.uid, "default", data)
+		require.NoError(t, err)
+		return string(out)
+	}
+
+	assert.Equal(t, `<a href="/blog/alpha">alpha</a><a href="/blog/beta">Beta</a> 1/2`, render(map[string]string{}))
+	assert.Equal(t, `<a href="/blog/gamma">Gamma</a><a href="/">Home</a> 2/2`, render(map[string]string{"page": "2"}))
+	assert.Equal(t, `<a href="/blog/alpha">alpha</a><a href="/blog/gamma">Gamma</a> 1/1`, render(map[string]string{"tag": "go"}))
+	assert.Equal(t, "none", render(map[string]string{"tag": "missing"}))
+
+	_, err = tm.Render(context.Background(), c.uid, "default", map[string]string{})
+	assert.Error(t, err, "pages is only available when rendering a page")
+}
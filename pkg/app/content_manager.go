@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/core"
 	"github.com/aarongodin/pagebin/pkg/store"
 	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/oklog/ulid/v2"
@@ -12,16 +13,25 @@ import (
 // ContentManager wraps logic for retrieving content for rendering
 type ContentManager interface {
 	Get(ctx context.Context, uid ulid.ULID) ([]byte, error)
+	// GetHTML returns content converted to HTML from its content type.
+	GetHTML(ctx context.Context, uid ulid.ULID, contentType string) ([]byte, error)
 	Refresh(uid ulid.ULID)
 }
 
+// contentKey identifies a cache entry. Raw content, which is also HTML content, has an empty content type, and converted content is cached
+// alongside it under each content type it was converted from.
+type contentKey struct {
+	uid         ulid.ULID
+	contentType string
+}
+
 type cachedContentManager struct {
-	cache *lru.Cache[ulid.ULID, []byte]
+	cache *lru.Cache[contentKey, []byte]
 	blob  store.BlobStore
 }
 
 func (m cachedContentManager) Get(ctx context.Context, uid ulid.ULID) ([]byte, error) {
-	raw, cached := m.cache.Get(uid)
+	raw, cached := m.cache.Get(contentKey{uid: uid})
 	if cached {
 		return raw, nil
 	}
@@ -29,17 +39,41 @@ func (m cachedContentManager) Get(ctx context.Context, uid ulid.ULID) ([]byte, e
 	if err != nil {
 		return nil, err
 	}
-	m.cache.Add(uid, raw)
+	m.cache.Add(contentKey{uid: uid}, raw)
 	return raw, nil
 }
 
+func (m cachedContentManager) GetHTML(ctx context.Context, uid ulid.ULID, contentType string) ([]byte, error) {
+	key := contentKey{uid, contentType}
+	if contentType == "" || contentType == core.ContentTypeHTML {
+		return m.Get(ctx, uid)
+	}
+	output, cached := m.cache.Get(key)
+	if cached {
+		return output, nil
+	}
+	raw, err := m.Get(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	output, err = convertContent(contentType, raw)
+	if err != nil {
+		return nil, err
+	}
+	m.cache.Add(key, output)
+	return output, nil
+}
+
 func (m cachedContentManager) Refresh(uid ulid.ULID) {
-	m.cache.Remove(uid)
+	m.cache.Remove(contentKey{uid: uid})
+	for _, contentType := range contentTypes.names() {
+		m.cache.Remove(contentKey{uid, contentType})
+	}
 }
 
 // NewCachedContentManager creates a content manager backed by an LRU cache. Configure the cache size and options through runtime config.
 func NewCachedContentManager(rc *config.RuntimeConfig, blob store.BlobStore) (ContentManager, error) {
-	cache, err := lru.New[ulid.ULID, []byte](rc.ContentCacheSize)
+	cache, err := lru.New[contentKey, []byte](rc.ContentCacheSize)
 	if err != nil {
 		return nil, err
 	}
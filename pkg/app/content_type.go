@@ -0,0 +1,109 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// ContentConverter converts page content of a content type to HTML.
+type ContentConverter func(content []byte) ([]byte, error)
+
+// contentTypeRegistry holds the converters for content types that render to HTML without a theme. Block documents are
+// rendered with the block templates of a theme, so they are handled separately and are not in the registry.
+type contentTypeRegistry struct {
+	mu         sync.RWMutex
+	converters map[string]ContentConverter
+}
+
+var contentTypes = &contentTypeRegistry{
+	converters: map[string]ContentConverter{
+		core.ContentTypeHTML:     convertHTML,
+		core.ContentTypeMarkdown: convertMarkdown,
+	},
+}
+
+// markdown is CommonMark with GitHub tables, strikethrough, autolinks and task lists, plus footnotes and heading IDs.
+// Raw HTML is passed through, the same as HTML page content.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// RegisterContentType adds a content type that pages may be written with. Registering a name twice, or the name of a
+// built-in content type, panics.
+func RegisterContentType(name string, convert ContentConverter) {
+	if name == "" || name == core.ContentTypeBlocks {
+		panic(fmt.Sprintf("content type \"%s\" is reserved", name))
+	}
+	contentTypes.mu.Lock()
+	defer contentTypes.mu.Unlock()
+	if _, exists := contentTypes.converters[name]; exists {
+		panic(fmt.Sprintf("content type \"%s\" is already registered", name))
+	}
+	contentTypes.converters[name] = convert
+}
+
+// converter returns the converter for a content type. An empty content type is HTML.
+func (r *contentTypeRegistry) converter(contentType string) (ContentConverter, bool) {
+	if contentType == "" {
+		contentType = core.ContentTypeHTML
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	convert, ok := r.converters[contentType]
+	return convert, ok
+}
+
+// names lists the registered content types.
+func (r *contentTypeRegistry) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.converters))
+	for name := range r.converters {
+		names = append(names, name)
+	}
+	return names
+}
+
+// convertContent converts content to HTML with the converter of its content type.
+func convertContent(contentType string, content []byte) ([]byte, error) {
+	convert, ok := contentTypes.converter(contentType)
+	if !ok {
+		return nil, core.ErrInvalidContent.New("unknown content type \"%s\"", contentType)
+	}
+	output, err := convert(content)
+	if err != nil {
+		return nil, core.ErrInvalidContent.Wrap(err, "content could not be converted from %s", contentType)
+	}
+	return output, nil
+}
+
+func convertHTML(content []byte) ([]byte, error) {
+	return content, nil
+}
+
+func convertMarkdown(content []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if err := markdown.Convert(content, &out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// validateContent rejects content that cannot be rendered as its content type.
+func validateContent(contentType string, content []byte) error {
+	if contentType == core.ContentTypeBlocks {
+		_, err := parseBlockDocument(content)
+		return err
+	}
+	_, err := convertContent(contentType, content)
+	return err
+}
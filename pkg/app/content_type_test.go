@@ -0,0 +1,49 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMarkdown(t *testing.T) {
+	source := "# Getting Started\n\n" +
+		"Some *text* with a note.[^1]\n\n" +
+		"| Name | Value |\n| --- | --- |\n| a | 1 |\n\n" +
+		"<div class=\"raw\">kept</div>\n\n" +
+		"[^1]: The note.\n"
+	output, err := convertContent(core.ContentTypeMarkdown, []byte(source))
+	require.NoError(t, err)
+	html := string(output)
+	assert.Contains(t, html, `<h1 id="getting-started">Getting Started</h1>`)
+	assert.Contains(t, html, "<em>text</em>")
+	assert.Contains(t, html, "<table>")
+	assert.Contains(t, html, "<td>a</td>")
+	assert.Contains(t, html, `<div class="raw">kept</div>`)
+	assert.Contains(t, html, `class="footnotes"`)
+	assert.Contains(t, html, `href="#fn:1"`)
+}
+
+func TestConvertContent(t *testing.T) {
+	output, err := convertContent("", []byte("<p>hi</p>"))
+	require.NoError(t, err)
+	assert.Equal(t, "<p>hi</p>", string(output))
+
+	_, err = convertContent("rst", []byte("hi"))
+	assert.True(t, core.IsInvalid(err))
+
+	RegisterContentType("upper", func(content []byte) ([]byte, error) {
+		return []byte(strings.ToUpper(string(content))), nil
+	})
+	output, err = convertContent("upper", []byte("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "HI", string(output))
+	assert.NoError(t, validateContent("upper", []byte("hi")))
+
+	assert.Panics(t, func() { RegisterContentType("upper", convertHTML) })
+	assert.Panics(t, func() { RegisterContentType(core.ContentTypeBlocks, convertHTML) })
+	assert.Panics(t, func() { RegisterContentType(core.ContentTypeMarkdown, convertHTML) })
+}
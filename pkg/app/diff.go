@@ -0,0 +1,259 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/aarongodin/pagebin/pkg/store"
+	"github.com/oklog/ulid/v2"
+)
+
+// versionDiffer compares two versions. Pages are copied when edited, so a page UID found in both versions always has
+// the same content; pages at the same path with different UIDs are compared by content hash and metadata. Since moving
+// a page shared with another version also copies it, a removed page and an added page with the same content and
+// metadata are reported as moved.
+type versionDiffer struct {
+	store store.Store
+}
+
+func (d versionDiffer) diff(ctx context.Context, from core.Version, to core.Version) (core.VersionDiff, error) {
+	diff := core.VersionDiff{
+		From:            from.UID,
+		To:              to.UID,
+		Added:           []core.PageChange{},
+		Removed:         []core.PageChange{},
+		Moved:           []core.PageChange{},
+		ContentChanged:  []core.PageChange{},
+		MetadataChanged: []core.PageChange{},
+	}
+	fromPaths := pathsByUID(from)
+	toPaths := pathsByUID(to)
+
+	for _, path := range sortedPaths(to) {
+		uid := to.Pages[path]
+		if previousPath, exists := fromPaths[uid]; exists {
+			if previousPath != path {
+				diff.Moved = append(diff.Moved, core.PageChange{Path: path, UID: uid, PreviousPath: previousPath, PreviousUID: uid})
+			}
+			continue
+		}
+		previousUID, exists := from.Pages[path]
+		if !exists || hasUID(toPaths, previousUID) {
+			diff.Added = append(diff.Added, core.PageChange{Path: path, UID: uid})
+			continue
+		}
+		contentChanged, fields, err := d.comparePages(ctx, previousUID, uid)
+		if err != nil {
+			return core.VersionDiff{}, err
+		}
+		change := core.PageChange{Path: path, UID: uid, PreviousPath: path, PreviousUID: previousUID}
+		if contentChanged {
+			diff.ContentChanged = append(diff.ContentChanged, change)
+		}
+		if len(fields) > 0 {
+			change.Fields = fields
+			diff.MetadataChanged = append(diff.MetadataChanged, change)
+		}
+	}
+
+	for _, path := range sortedPaths(from) {
+		uid := from.Pages[path]
+		if hasUID(toPaths, uid) {
+			continue
+		}
+		if currentUID, exists := to.Pages[path]; exists && !hasUID(fromPaths, currentUID) {
+			continue
+		}
+		diff.Removed = append(diff.Removed, core.PageChange{PreviousPath: path, PreviousUID: uid})
+	}
+
+	if err := d.pairMoves(ctx, &diff); err != nil {
+		return core.VersionDiff{}, err
+	}
+
+	if from.Theme != to.Theme {
+		theme, err := d.compareThemes(ctx, from.Theme, to.Theme)
+		if err != nil {
+			return core.VersionDiff{}, err
+		}
+		diff.Theme = &theme
+	}
+	return diff, nil
+}
+
+// pairMoves reports removed and added pages with the same content and metadata as moved, pairing them in path order.
+func (d versionDiffer) pairMoves(ctx context.Context, diff *core.VersionDiff) error {
+	if len(diff.Added) == 0 || len(diff.Removed) == 0 {
+		return nil
+	}
+	removed := map[string][]int{}
+	for i, change := range diff.Removed {
+		key, err := d.pageKey(ctx, change.PreviousUID)
+		if err != nil {
+			return err
+		}
+		removed[key] = append(removed[key], i)
+	}
+	paired := map[int]bool{}
+	added := diff.Added[:0]
+	for _, change := range diff.Added {
+		key, err := d.pageKey(ctx, change.UID)
+		if err != nil {
+			return err
+		}
+		if candidates := removed[key]; len(candidates) > 0 {
+			previous := diff.Removed[candidates[0]]
+			removed[key] = candidates[1:]
+			paired[candidates[0]] = true
+			change.PreviousPath, change.PreviousUID = previous.PreviousPath, previous.PreviousUID
+			diff.Moved = append(diff.Moved, change)
+			continue
+		}
+		added = append(added, change)
+	}
+	diff.Added = added
+	kept := []core.PageChange{}
+	for i, change := range diff.Removed {
+		if !paired[i] {
+			kept = append(kept, change)
+		}
+	}
+	diff.Removed = kept
+	slices.SortFunc(diff.Moved, func(a, b core.PageChange) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return nil
+}
+
+// pageKey identifies the content and metadata of a page, leaving out its path.
+func (d versionDiffer) pageKey(ctx context.Context, uid ulid.ULID) (string, error) {
+	page, err := d.store.Pages().GetPage(ctx, uid)
+	if err != nil {
+		return "", err
+	}
+	blob, err := d.store.Blobs().GetBlob(ctx, page.Content)
+	if err != nil {
+		return "", err
+	}
+	key, err := json.Marshal([]any{blob.Hash, page.Title, page.TemplateName, page.Tags, page.Excerpt, page.ContentType, page.NoIndex})
+	return string(key), err
+}
+
+// comparePages reports whether the content of two pages differs and which metadata fields differ.
+func (d versionDiffer) comparePages(ctx context.Context, fromUID ulid.ULID, toUID ulid.ULID) (bool, []string, error) {
+	from, err := d.store.Pages().GetPage(ctx, fromUID)
+	if err != nil {
+		return false, nil, err
+	}
+	to, err := d.store.Pages().GetPage(ctx, toUID)
+	if err != nil {
+		return false, nil, err
+	}
+	contentChanged, err := d.blobsDiffer(ctx, from.Content, to.Content)
+	if err != nil {
+		return false, nil, err
+	}
+	fields := []string{}
+	if from.Title != to.Title {
+		fields = append(fields, "title")
+	}
+	if from.TemplateName != to.TemplateName {
+		fields = append(fields, "templateName")
+	}
+	if !slices.Equal(from.Tags, to.Tags) {
+		fields = append(fields, "tags")
+	}
+	if from.Excerpt != to.Excerpt {
+		fields = append(fields, "excerpt")
+	}
+	if from.ContentType != to.ContentType {
+		fields = append(fields, "contentType")
+	}
+	if from.NoIndex != to.NoIndex {
+		fields = append(fields, "noIndex")
+	}
+	return contentChanged, fields, nil
+}
+
+func (d versionDiffer) compareThemes(ctx context.Context, fromUID ulid.ULID, toUID ulid.ULID) (core.ThemeChange, error) {
+	change := core.ThemeChange{
+		From:             fromUID,
+		To:               toUID,
+		TemplatesAdded:   []string{},
+		TemplatesRemoved: []string{},
+		TemplatesChanged: []string{},
+	}
+	from, err := d.store.Themes().GetTheme(ctx, fromUID)
+	if err != nil {
+		return change, err
+	}
+	to, err := d.store.Themes().GetTheme(ctx, toUID)
+	if err != nil {
+		return change, err
+	}
+	for name, toBlob := range to.Templates {
+		fromBlob, exists := from.Templates[name]
+		if !exists {
+			change.TemplatesAdded = append(change.TemplatesAdded, name)
+			continue
+		}
+		changed, err := d.blobsDiffer(ctx, fromBlob, toBlob)
+		if err != nil {
+			return change, err
+		}
+		if changed {
+			change.TemplatesChanged = append(change.TemplatesChanged, name)
+		}
+	}
+	for name := range from.Templates {
+		if _, exists := to.Templates[name]; !exists {
+			change.TemplatesRemoved = append(change.TemplatesRemoved, name)
+		}
+	}
+	slices.Sort(change.TemplatesAdded)
+	slices.Sort(change.TemplatesRemoved)
+	slices.Sort(change.TemplatesChanged)
+	change.AssetsChanged = !slices.Equal(from.CSSAssets, to.CSSAssets) || !slices.Equal(from.JSAssets, to.JSAssets) ||
+		from.AssetOptions != to.AssetOptions
+	return change, nil
+}
+
+func (d versionDiffer) blobsDiffer(ctx context.Context, fromUID ulid.ULID, toUID ulid.ULID) (bool, error) {
+	if fromUID == toUID {
+		return false, nil
+	}
+	from, err := d.store.Blobs().GetBlob(ctx, fromUID)
+	if err != nil {
+		return false, err
+	}
+	to, err := d.store.Blobs().GetBlob(ctx, toUID)
+	if err != nil {
+		return false, err
+	}
+	return !slices.Equal(from.Hash, to.Hash), nil
+}
+
+func pathsByUID(version core.Version) map[ulid.ULID]string {
+	paths := make(map[ulid.ULID]string, len(version.Pages))
+	for path, uid := range version.Pages {
+		paths[uid] = path
+	}
+	return paths
+}
+
+func hasUID(paths map[ulid.ULID]string, uid ulid.ULID) bool {
+	_, exists := paths[uid]
+	return exists
+}
+
+func sortedPaths(version core.Version) []string {
+	paths := make([]string, 0, len(version.Pages))
+	for path := range version.Pages {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func changePaths(changes []core.PageChange) [][2]string {
+	paths := [][2]string{}
+	for _, change := range changes {
+		paths = append(paths, [2]string{change.PreviousPath, change.Path})
+	}
+	return paths
+}
+
+// diffTestService publishes a version with pages to change and changes them in the next version.
+func diffTestService(t *testing.T) (*Svc, core.Site) {
+	t.Helper()
+	ctx := context.Background()
+	s := newTestService(t)
+	target := nextTarget(t, s)
+	pages := map[string]core.Page{}
+	for _, path := range []string{"/content", "/metadata", "/move", "/remove"} {
+		pages[path] = putTestPage(t, s, target, nil, path, "# "+path)
+	}
+	site := publishTest(t, s)
+
+	target = core.NewNextTargetVersion(site.NextVersion)
+	putTestPage(t, s, target, nil, "/add", "# Add")
+	require.NoError(t, s.DeletePage(ctx, target, pages["/remove"].UID))
+	moved := pages["/move"]
+	write := core.WritablePage{
+		Title:        moved.Title,
+		Path:         "/moved",
+		TemplateName: moved.TemplateName,
+		Tags:         moved.Tags,
+		ContentType:  moved.ContentType,
+	}
+	_, err := s.PutPage(ctx, target, &moved.UID, write, []byte("# /move"))
+	require.NoError(t, err)
+	content := pages["/content"]
+	putTestPage(t, s, target, &content.UID, "/content", "# Changed")
+	metadata := pages["/metadata"]
+	_, err = s.PutPage(ctx, target, &metadata.UID, core.WritablePage{
+		Title:        "Changed",
+		Path:         "/metadata",
+		TemplateName: "default",
+		Tags:         []string{"changed"},
+		ContentType:  core.ContentTypeMarkdown,
+	}, []byte("# /metadata"))
+	require.NoError(t, err)
+	return s, site
+}
+
+func TestDiffVersions(t *testing.T) {
+	s, site := diffTestService(t)
+	diff, err := s.DiffVersions(context.Background(), site.Version, site.NextVersion)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][2]string{{"", "/add"}}, changePaths(diff.Added))
+	assert.Equal(t, [][2]string{{"/remove", ""}}, changePaths(diff.Removed))
+	assert.Equal(t, [][2]string{{"/move", "/moved"}}, changePaths(diff.Moved), "a copied page that only moved is moved")
+	assert.Equal(t, [][2]string{{"/content", "/content"}}, changePaths(diff.ContentChanged))
+	require.Len(t, diff.MetadataChanged, 1)
+	assert.Equal(t, "/metadata", diff.MetadataChanged[0].Path)
+	assert.Equal(t, []string{"title", "tags"}, diff.MetadataChanged[0].Fields)
+	assert.Nil(t, diff.Theme)
+
+	reverse, err := s.DiffVersions(context.Background(), site.NextVersion, site.Version)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]string{{"", "/remove"}}, changePaths(reverse.Added))
+	assert.Equal(t, [][2]string{{"/add", ""}}, changePaths(reverse.Removed))
+	assert.Equal(t, [][2]string{{"/moved", "/move"}}, changePaths(reverse.Moved))
+}
+
+func TestDiffVersionsTheme(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	from, err := s.GetVersion(ctx, site.Version)
+	require.NoError(t, err)
+	theme, err := s.store.Themes().GetTheme(ctx, from.Theme)
+	require.NoError(t, err)
+
+	require.Contains(t, theme.Templates, "default")
+	blob, err := s.store.Blobs().CreateBlob(ctx, []byte("changed"))
+	require.NoError(t, err)
+	templates := map[string]ulid.ULID{}
+	for name, uid := range theme.Templates {
+		templates[name] = uid
+	}
+	templates["default"] = blob.UID
+	templates["added"] = blob.UID
+	other, err := s.store.Themes().CreateTheme(ctx, templates, theme.CSSAssets, theme.JSAssets, theme.AssetOptions)
+	require.NoError(t, err)
+
+	to := from
+	to.UID = ulid.Make()
+	to.Theme = other.UID
+	diff, err := versionDiffer{s.store}.diff(ctx, from, to)
+	require.NoError(t, err)
+	require.NotNil(t, diff.Theme)
+	assert.Equal(t, []string{"added"}, diff.Theme.TemplatesAdded)
+	assert.Equal(t, []string{}, diff.Theme.TemplatesRemoved)
+	assert.Equal(t, []string{"default"}, diff.Theme.TemplatesChanged)
+	assert.False(t, diff.Theme.AssetsChanged)
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Removed)
+
+	diff, err = versionDiffer{s.store}.diff(ctx, to, from)
+	require.NoError(t, err)
+	require.NotNil(t, diff.Theme)
+	assert.Equal(t, []string{"added"}, diff.Theme.TemplatesRemoved)
+}
+
+func TestDiffVersionsAPI(t *testing.T) {
+	s, site := diffTestService(t)
+	app := newApp(s.rc, s, newErrorHandler(s))
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/versions/current/diff/next", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var diff core.VersionDiff
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&diff))
+	assert.Equal(t, site.Version, diff.From)
+	assert.Equal(t, site.NextVersion, diff.To)
+	assert.Len(t, diff.Moved, 1)
+
+	res, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/versions/current/diff/"+ulid.Make().String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/versions/current/diff/nope", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/joomcode/errorx"
+	"github.com/rs/zerolog/log"
+)
+
+// templateError is the theme template rendered for any error status without a template of its own.
+const templateError = "error"
+
+var builtinErrorTemplate = `<!doctype html>
+<html>
+<head>
+	<title>%[1]d %[2]s</title>
+</head>
+<body>
+	<h1>%[1]d %[2]s</h1>
+	<p>%[3]s</p>
+</body>
+</html>
+`
+
+type errorBody struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type errorHandler struct {
+	service Service
+}
+
+// handle responds to errors returned by handlers. Admin API errors are sent as JSON, while errors from rendering are
+// sent as an error page from the theme of the requested version. The theme template named for the status code is
+// used, such as "404", then the "error" template, and finally a built-in page.
+func (h errorHandler) handle(ctx *fiber.Ctx, err error) error {
+	status := errorStatus(err)
+	if status >= http.StatusInternalServerError {
+		log.Error().Err(err).Str("path", ctx.Path()).Msg("request failed")
+	}
+	message := errorMessage(err, status)
+
+	if strings.HasPrefix(ctx.Path(), pathAPI) {
+		body := errorBody{Message: message}
+		if e := errorx.Cast(err); e != nil {
+			body.Type = e.Type().FullName()
+		}
+		return ctx.Status(status).JSON(body)
+	}
+
+	ctx.Response().Header.Del(fiber.HeaderETag)
+	ctx.Response().Header.Del(fiber.HeaderLastModified)
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	ctx.Type("html")
+	output, renderErr := h.render(ctx, status, message)
+	if renderErr != nil {
+		log.Error().Err(renderErr).Str("path", ctx.Path()).Msg("error page failed to render")
+		output = builtinErrorPage(status, message)
+	}
+	return ctx.Status(status).Send(output)
+}
+
+func (h errorHandler) render(ctx *fiber.Ctx, status int, message string) ([]byte, error) {
+	site, err := h.service.GetSite(ctx.Context())
+	if err != nil {
+		return nil, err
+	}
+	// The requested version may itself be the cause of the error, in which case the current theme is used.
+	target, err := getTargetVersion(ctx, h.service, false)
+	if err != nil {
+		target = core.NewCurrentTargetVersion(site.Version)
+	}
+	version, err := h.service.GetVersion(ctx.Context(), target.UID())
+	if err != nil {
+		return nil, err
+	}
+	data := newErrorRenderContext(ctx, site, version, target, status, message)
+	data.pages = newPageQuerier(ctx.Context(), h.service.Collections(), version.UID)
+	for _, templateName := range []string{strconv.Itoa(status), templateError} {
+		output, err := h.service.ThemeManager().Render(ctx.Context(), version.Theme, templateName, data)
+		if errorx.IsOfType(err, core.ErrThemeTemplateNotFound) {
+			continue
+		}
+		return output, err
+	}
+	return builtinErrorPage(status, message), nil
+}
+
+// builtinErrorPage is the error page used when the theme has no error templates or they fail to render.
+func builtinErrorPage(status int, message string) []byte {
+	return []byte(fmt.Sprintf(builtinErrorTemplate, status, http.StatusText(status), html.EscapeString(message)))
+}
+
+// errorStatus maps an error to the status code of its response.
+func errorStatus(err error) int {
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &fiberErr):
+		return fiberErr.Code
+	case errorx.IsNotFound(err):
+		return http.StatusNotFound
+	case core.IsInvalid(err):
+		return http.StatusBadRequest
+	case core.IsConflict(err):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// errorMessage describes an error for a response. Server errors are not described beyond their status, since their
+// messages may include internal details.
+func errorMessage(err error, status int) string {
+	if status >= http.StatusInternalServerError {
+		return http.StatusText(status)
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Message
+	}
+	if e := errorx.Cast(err); e != nil && e.Message() != "" {
+		return e.Message()
+	}
+	return http.StatusText(status)
+}
+
+func newErrorHandler(service Service) fiber.ErrorHandler {
+	return errorHandler{service}.handle
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorStatus(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected int
+	}{
+		{core.ErrPageNotFound.NewWithNoMessage(), http.StatusNotFound},
+		{core.ErrReservedPath.NewWithNoMessage(), http.StatusNotFound},
+		{core.ErrItemNotFound.New("missing"), http.StatusNotFound},
+		{core.ErrInvalidVersion.New("bad version"), http.StatusBadRequest},
+		{core.ErrInvalidPath.New("bad path"), http.StatusBadRequest},
+		{core.ErrMergeConflict.New("conflict"), http.StatusConflict},
+		{core.ErrThemeTemplateExec.New("failed"), http.StatusInternalServerError},
+		{fiber.ErrMethodNotAllowed, http.StatusMethodNotAllowed},
+		{errors.New("unknown"), http.StatusInternalServerError},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, errorStatus(tc.err), tc.err.Error())
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	assert.Equal(t, "bad version", errorMessage(core.ErrInvalidVersion.New("bad version"), http.StatusBadRequest))
+	assert.Equal(t, "Not Found", errorMessage(core.ErrPageNotFound.NewWithNoMessage(), http.StatusNotFound))
+	assert.Equal(t, "Internal Server Error", errorMessage(core.ErrThemeTemplateExec.New("secret"), http.StatusInternalServerError))
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/oklog/ulid/v2"
+)
+
+// pageETag is a strong entity tag for a rendered page. It changes whenever anything that goes into the rendered output
+// changes: the version and its theme, the page, its metadata and its content, the site title, or the revision of the
+// pages of the version. Pages of versions other than the current version are edited in place, so the metadata is
+// part of the tag as well as the UID.
+func pageETag(version ulid.ULID, page core.Page, contentHash []byte, theme ulid.ULID, siteTitle string, revision string) string {
+	h := sha256.New()
+	h.Write(version[:])
+	h.Write(page.UID[:])
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(page.UpdatedAt.UnixNano())))
+	for _, field := range append([]string{page.Title, page.TemplateName, page.Excerpt, page.ContentType, strconv.FormatBool(page.NoIndex)}, page.Tags...) {
+		// fields are prefixed with their length so that they cannot run into each other
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+		h.Write([]byte(field))
+	}
+	h.Write(contentHash)
+	h.Write(theme[:])
+	h.Write([]byte(siteTitle))
+	h.Write([]byte(revision))
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// bodyETag is a strong entity tag for a generated document that is cheap to generate again, such as a feed.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// setValidators sets the ETag and Last-Modified response headers. A zero lastModified omits the header.
+func setValidators(ctx *fiber.Ctx, etag string, lastModified time.Time) {
+	ctx.Set(fiber.HeaderETag, etag)
+	if !lastModified.IsZero() {
+		ctx.Set(fiber.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since as described in RFC 9110. If-Modified-Since is ignored
+// when If-None-Match is present.
+func notModified(ctx *fiber.Ctx, etag string, lastModified time.Time) bool {
+	if noneMatch := ctx.Get(fiber.HeaderIfNoneMatch); noneMatch != "" {
+		for _, candidate := range strings.Split(noneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if modifiedSince := ctx.Get(fiber.HeaderIfModifiedSince); modifiedSince != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(modifiedSince)
+		return err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageETag(t *testing.T) {
+	version, theme := ulid.Make(), ulid.Make()
+	page := core.Page{
+		UID:          ulid.Make(),
+		Title:        "Hello",
+		TemplateName: "default",
+		Tags:         []string{"a"},
+		UpdatedAt:    time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC),
+	}
+	etag := pageETag(version, page, []byte("hash"), theme, "Site", "")
+	assert.Equal(t, etag, pageETag(version, page, []byte("hash"), theme, "Site", ""))
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+
+	changes := map[string]func(p *core.Page){
+		"title":        func(p *core.Page) { p.Title = "Bye" },
+		"template":     func(p *core.Page) { p.TemplateName = "post" },
+		"tags":         func(p *core.Page) { p.Tags = []string{"b"} },
+		"excerpt":      func(p *core.Page) { p.Excerpt = "Hi" },
+		"content type": func(p *core.Page) { p.ContentType = core.ContentTypeMarkdown },
+		"no index":     func(p *core.Page) { p.NoIndex = true },
+		"updated at":   func(p *core.Page) { p.UpdatedAt = p.UpdatedAt.Add(time.Nanosecond) },
+		"fields run together": func(p *core.Page) {
+			p.Title, p.TemplateName = "Hellodefault", ""
+		},
+	}
+	for desc, change := range changes {
+		changed := page
+		changed.Tags = append([]string{}, page.Tags...)
+		change(&changed)
+		assert.NotEqual(t, etag, pageETag(version, changed, []byte("hash"), theme, "Site", ""), desc)
+	}
+	assert.NotEqual(t, etag, pageETag(version, page, []byte("other"), theme, "Site", ""))
+	assert.NotEqual(t, etag, pageETag(version, page, []byte("hash"), ulid.Make(), "Site", ""))
+	assert.NotEqual(t, etag, pageETag(version, page, []byte("hash"), theme, "Other", ""))
+	assert.NotEqual(t, etag, pageETag(version, page, []byte("hash"), theme, "Site", "1"))
+}
+
+func TestConditionalRequests(t *testing.T) {
+	const etag = `"abc"`
+	lastModified := time.Date(2024, time.March, 5, 14, 30, 15, 500, time.UTC)
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		setValidators(ctx, etag, lastModified)
+		if notModified(ctx, etag, lastModified) {
+			return ctx.SendStatus(http.StatusNotModified)
+		}
+		return ctx.SendString("body")
+	})
+	app.Get("/no-last-modified", func(ctx *fiber.Ctx) error {
+		setValidators(ctx, etag, time.Time{})
+		if notModified(ctx, etag, time.Time{}) {
+			return ctx.SendStatus(http.StatusNotModified)
+		}
+		return ctx.SendString("body")
+	})
+
+	testCases := []struct {
+		desc    string
+		path    string
+		headers map[string]string
+		status  int
+	}{
+		{"no validators", "/", nil, http.StatusOK},
+		{"matching etag", "/", map[string]string{fiber.HeaderIfNoneMatch: etag}, http.StatusNotModified},
+		{"weak matching etag", "/", map[string]string{fiber.HeaderIfNoneMatch: `W/"abc"`}, http.StatusNotModified},
+		{"etag in list", "/", map[string]string{fiber.HeaderIfNoneMatch: `"x", "abc"`}, http.StatusNotModified},
+		{"any etag", "/", map[string]string{fiber.HeaderIfNoneMatch: "*"}, http.StatusNotModified},
+		{"other etag", "/", map[string]string{fiber.HeaderIfNoneMatch: `"x"`}, http.StatusOK},
+		{"not modified since", "/", map[string]string{fiber.HeaderIfModifiedSince: "Tue, 05 Mar 2024 14:30:15 GMT"}, http.StatusNotModified},
+		{"modified since", "/", map[string]string{fiber.HeaderIfModifiedSince: "Tue, 05 Mar 2024 14:30:14 GMT"}, http.StatusOK},
+		{"invalid date", "/", map[string]string{fiber.HeaderIfModifiedSince: "yesterday"}, http.StatusOK},
+		{"etag takes precedence", "/", map[string]string{
+			fiber.HeaderIfNoneMatch:     `"x"`,
+			fiber.HeaderIfModifiedSince: "Tue, 05 Mar 2024 14:30:15 GMT",
+		}, http.StatusOK},
+		{"no last modified", "/no-last-modified", map[string]string{fiber.HeaderIfModifiedSince: "Tue, 05 Mar 2024 14:30:15 GMT"}, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		for key, value := range tc.headers {
+			req.Header.Set(key, value)
+		}
+		res, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tc.status, res.StatusCode, tc.desc)
+		assert.Equal(t, etag, res.Header.Get(fiber.HeaderETag), tc.desc)
+		if tc.path == "/" {
+			assert.Equal(t, "Tue, 05 Mar 2024 14:30:15 GMT", res.Header.Get(fiber.HeaderLastModified), tc.desc)
+		} else {
+			assert.Empty(t, res.Header.Get(fiber.HeaderLastModified), tc.desc)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// exportReportFile is the file that the admin API adds the export report to.
+	exportReportFile = "pagebin-export.json"
+	// headerExportError carries the error of a failed request back to the exporter. It is only set on the requests of
+	// an export, which never leave the process.
+	headerExportError = "X-Pagebin-Export-Error"
+	// defaultExportBaseURL makes the URLs of feeds and the sitemap absolute when the site has no URL.
+	defaultExportBaseURL = "http://localhost"
+	// exportNotFoundFile is the page that most static hosts serve for missing paths.
+	exportNotFoundFile = "404.html"
+)
+
+// exportLink matches root-relative URLs in the href, src, action and poster attributes of HTML.
+var exportLink = regexp.MustCompile(`(?i)(\s(?:href|src|action|poster)\s*=\s*)(["'])(/(?:[^/"'][^"']*)?)(["'])`)
+
+// ExportOptions selects what an export renders.
+type ExportOptions struct {
+	// Version is "next", a draft name or a version UID, the same as the X-Pagebin-Version header. The current version
+	// is exported when it is empty.
+	Version string
+	// BaseURL is the scheme and host that feeds and the sitemap make URLs absolute with when the site has no URL.
+	BaseURL string
+}
+
+// ExportWriter stores the files of an export. Names are slash-separated paths relative to the root of the export.
+type ExportWriter interface {
+	WriteFile(name string, body []byte) error
+}
+
+type dirExportWriter struct {
+	dir string
+}
+
+// NewDirExportWriter writes the files of an export under dir.
+func NewDirExportWriter(dir string) ExportWriter {
+	return dirExportWriter{dir}
+}
+
+func (w dirExportWriter) WriteFile(name string, body []byte) error {
+	if !fs.ValidPath(name) {
+		return core.ErrInvalidPath.New("file \"%s\" is not a valid export path", name)
+	}
+	file := filepath.Join(w.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, body, 0o644)
+}
+
+// TarExportWriter writes the files of an export to a gzipped tarball. Close finishes the tarball.
+type TarExportWriter struct {
+	gz      *gzip.Writer
+	tar     *tar.Writer
+	modTime time.Time
+}
+
+func (w *TarExportWriter) WriteFile(name string, body []byte) error {
+	if !fs.ValidPath(name) {
+		return core.ErrInvalidPath.New("file \"%s\" is not a valid export path", name)
+	}
+	if err := w.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(body)),
+		ModTime:  w.modTime,
+	}); err != nil {
+		return err
+	}
+	_, err := w.tar.Write(body)
+	return err
+}
+
+func (w *TarExportWriter) Close() error {
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+func NewTarExportWriter(w io.Writer) *TarExportWriter {
+	gz := gzip.NewWriter(w)
+	return &TarExportWriter{gz: gz, tar: tar.NewWriter(gz), modTime: time.Now()}
+}
+
+// Export renders every path of a version to static files: its pages, exact redirects, feeds, sitemap, robots.txt and
+// theme assets, along with a 404.html page. Each path is requested from the same routes that serve the site, and
+// root-relative links in HTML are rewritten relative to the file they are in, so the files can be served from any
+// directory of a plain file server. Paths that fail to render are reported rather than failing the export.
+func (s *Svc) Export(ctx context.Context, options ExportOptions, w ExportWriter) (core.ExportReport, error) {
+	report := core.ExportReport{Files: []string{}, Skipped: []string{}, Failures: []core.ExportFailure{}}
+	site, err := s.GetSite(ctx)
+	if err != nil {
+		return report, err
+	}
+	target := core.NewCurrentTargetVersion(site.Version)
+	if options.Version != "" {
+		if target, err = resolveTargetVersion(ctx, s, site, options.Version); err != nil {
+			return report, err
+		}
+	}
+	version, err := s.GetVersion(ctx, target.UID())
+	if err != nil {
+		return report, err
+	}
+	report.Version = version.UID
+
+	baseURL := strings.TrimSuffix(options.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultExportBaseURL
+	}
+	e := exporter{
+		app:     newApp(s.rc, exportService{s, NewRenderCache(&config.RuntimeConfig{})}, exportErrorHandler(newErrorHandler(s))),
+		baseURL: baseURL,
+		w:       w,
+		report:  &report,
+	}
+	if !target.IsCurrent() {
+		e.version = version.UID.String()
+	}
+
+	paths, err := s.exportPaths(ctx, site, version, &report)
+	if err != nil {
+		return report, err
+	}
+	for _, p := range paths {
+		if err := e.export(p); err != nil {
+			return report, err
+		}
+	}
+	if err := e.exportNotFound(); err != nil {
+		return report, err
+	}
+	assets, err := s.ThemeManager().Assets(ctx, version.Theme)
+	if err != nil {
+		return report, err
+	}
+	for _, name := range assets {
+		if err := e.export(pathAssets + "/" + name); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// exportPaths lists the paths of a version that have a single file, and adds the others to the skipped paths of the
+// report.
+func (s *Svc) exportPaths(ctx context.Context, site core.Site, version core.Version, report *core.ExportReport) ([]string, error) {
+	var paths []string
+	for p := range version.Pages {
+		if strings.ContainsAny(p, string(routeParamPrefix)+string(routeWildcardPrefix)) {
+			report.Skipped = append(report.Skipped, p)
+		} else {
+			paths = append(paths, p)
+		}
+	}
+	for _, redirect := range version.Redirects {
+		if redirect.Kind == core.RedirectKindExact {
+			paths = append(paths, redirect.From)
+		} else {
+			report.Skipped = append(report.Skipped, redirect.From)
+		}
+	}
+
+	collection, err := s.Collections().Query(ctx, version.UID, PageQuery{Sort: SortPath})
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, page := range collection.pages {
+		for _, tag := range page.Tags {
+			// a tag with a slash cannot be a single path segment
+			if !slices.Contains(tags, tag) && !strings.Contains(tag, "/") {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	for _, feed := range siteFeeds(site) {
+		if !strings.Contains(feed.Path, feedTagParam) {
+			paths = append(paths, feed.Path)
+			continue
+		}
+		for _, tag := range tags {
+			paths = append(paths, strings.ReplaceAll(feed.Path, feedTagParam, tag))
+		}
+	}
+
+	paths = append(paths, pathSitemap, pathRobots)
+	if parts := (len(sitemapPages(collection.pages)) + sitemapMaxURLs - 1) / sitemapMaxURLs; parts > 1 {
+		for i := 1; i <= parts; i++ {
+			paths = append(paths, pathSitemaps+strconv.Itoa(i)+".xml")
+		}
+	}
+	slices.Sort(paths)
+	slices.Sort(report.Skipped)
+	return slices.Compact(paths), nil
+}
+
+// exportService serves the requests of an export with a disabled render cache, so that output with links to the base
+// URL of the export is not cached for the site, and output cached for the site is not exported.
+type exportService struct {
+	Service
+	cache RenderCache
+}
+
+func (s exportService) RenderCache() RenderCache {
+	return s.cache
+}
+
+// exportErrorHandler passes the error of a failed request to the exporter in a response header, so that the report
+// can describe it.
+func exportErrorHandler(handle fiber.ErrorHandler) fiber.ErrorHandler {
+	return func(ctx *fiber.Ctx, err error) error {
+		handleErr := handle(ctx, err)
+		ctx.Set(headerExportError, err.Error())
+		return handleErr
+	}
+}
+
+// exporter requests the paths of an export from the routes that serve the site.
+type exporter struct {
+	app     *fiber.App
+	baseURL string
+	// version is sent as the X-Pagebin-Version header, or is empty for the current version.
+	version string
+	w       ExportWriter
+	report  *core.ExportReport
+}
+
+func (e exporter) get(p string) (*http.Response, []byte, error) {
+	req := httptest.NewRequest(http.MethodGet, e.baseURL+(&url.URL{Path: p}).EscapedPath(), nil)
+	// the scheme of the URL is not part of the request that the routes see
+	req.Header.Set(fiber.HeaderXForwardedProto, req.URL.Scheme)
+	if e.version != "" {
+		req.Header.Set(HeaderPagebinVersion, e.version)
+	}
+	res, err := e.app.Test(req, -1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return res, body, err
+}
+
+// export writes the file of a path, or a page that redirects to the location of a redirect. Failures are added to the
+// report, while errors are only returned when a file cannot be written.
+func (e exporter) export(p string) error {
+	res, body, err := e.get(p)
+	if err != nil {
+		e.fail(p, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	name := exportFileName(p)
+	switch {
+	case res.StatusCode == http.StatusOK:
+		if strings.HasPrefix(res.Header.Get(fiber.HeaderContentType), fiber.MIMETextHTML) {
+			body = rewriteExportLinks(body, name)
+		}
+	case res.StatusCode >= 300 && res.StatusCode < 400:
+		body = exportRedirectPage(res.Header.Get(fiber.HeaderLocation), name)
+	default:
+		message := res.Header.Get(headerExportError)
+		if message == "" {
+			message = http.StatusText(res.StatusCode)
+		}
+		e.fail(p, res.StatusCode, message)
+		return nil
+	}
+	if err := e.w.WriteFile(name, body); err != nil {
+		if core.IsInvalid(err) {
+			e.fail(p, http.StatusBadRequest, err.Error())
+			return nil
+		}
+		return err
+	}
+	e.report.Files = append(e.report.Files, name)
+	return nil
+}
+
+// exportNotFound writes the page that the theme renders for a missing path, unless the version has a 404.html page.
+func (e exporter) exportNotFound() error {
+	if slices.Contains(e.report.Files, exportNotFoundFile) {
+		return nil
+	}
+	res, body, err := e.get("/" + exportNotFoundFile)
+	if err != nil || res.StatusCode != http.StatusNotFound {
+		return nil
+	}
+	if err := e.w.WriteFile(exportNotFoundFile, body); err != nil {
+		return err
+	}
+	e.report.Files = append(e.report.Files, exportNotFoundFile)
+	return nil
+}
+
+func (e exporter) fail(p string, status int, message string) {
+	e.report.Failures = append(e.report.Failures, core.ExportFailure{Path: p, Status: status, Message: message})
+}
+
+// exportFileName is the file a path is exported to. Paths whose last segment has an extension, such as /feed.xml,
+// are exported as is, while other paths are exported to an index.html file in a directory of the same name.
+func exportFileName(p string) string {
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		return "index.html"
+	}
+	if path.Ext(name) != "" && !strings.HasSuffix(p, "/") {
+		return name
+	}
+	return name + "/index.html"
+}
+
+// rewriteExportLinks makes the root-relative links of an HTML file relative to the file, pointing at the exported file
+// of the path they link to.
+func rewriteExportLinks(body []byte, name string) []byte {
+	return exportLink.ReplaceAllFunc(body, func(match []byte) []byte {
+		groups := exportLink.FindSubmatch(match)
+		link := exportRelativeURL(html.UnescapeString(string(groups[3])), name)
+		return []byte(string(groups[1]) + string(groups[2]) + link + string(groups[4]))
+	})
+}
+
+// exportRelativeURL returns a root-relative URL as a URL relative to the exported file name, keeping its query and
+// fragment.
+func exportRelativeURL(link string, name string) string {
+	p, suffix := link, ""
+	if i := strings.IndexAny(link, "?#"); i >= 0 {
+		p, suffix = link[:i], link[i:]
+	}
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(name)), filepath.FromSlash(exportFileName(p)))
+	if err != nil {
+		return link
+	}
+	return html.EscapeString((&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath() + suffix)
+}
+
+// exportRedirectPage is a page that redirects to location, since a plain file server cannot send redirects.
+func exportRedirectPage(location string, name string) []byte {
+	if strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//") {
+		location = exportRelativeURL(location, name)
+	} else {
+		location = html.EscapeString(location)
+	}
+	return []byte(`<!doctype html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta http-equiv="refresh" content="0; url=` + location + `">
+	<link rel="canonical" href="` + location + `">
+</head>
+<body>
+	<a href="` + location + `">Moved here</a>
+</body>
+</html>
+`)
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportFileName(t *testing.T) {
+	testCases := map[string]string{
+		"/":                  "index.html",
+		"/about":             "about/index.html",
+		"/about/":            "about/index.html",
+		"/blog/post-1":       "blog/post-1/index.html",
+		"/feed.xml":          "feed.xml",
+		"/tags/go/feed.json": "tags/go/feed.json",
+		"/_assets/a1b2.css":  "_assets/a1b2.css",
+		"/../etc":            "etc/index.html",
+	}
+	for p, expected := range testCases {
+		assert.Equal(t, expected, exportFileName(p), p)
+	}
+}
+
+func TestRewriteExportLinks(t *testing.T) {
+	body := []byte(`<a href="/">home</a> <a href='/blog/post-1?page=2#top'>post</a> <img src="/img/a%20b.png">` +
+		`<a href="https://example.com/x">external</a> <a href="//cdn.example.com/x.js">cdn</a> <a href="#top">top</a>` +
+		`<link rel="stylesheet" href="/_assets/a1b2.css">`)
+	expected := `<a href="../../index.html">home</a> <a href='index.html?page=2#top'>post</a> <img src="../../img/a%20b.png">` +
+		`<a href="https://example.com/x">external</a> <a href="//cdn.example.com/x.js">cdn</a> <a href="#top">top</a>` +
+		`<link rel="stylesheet" href="../../_assets/a1b2.css">`
+	assert.Equal(t, expected, string(rewriteExportLinks(body, "blog/post-1/index.html")))
+	assert.Equal(t, `<a href="about/index.html">about</a>`, string(rewriteExportLinks([]byte(`<a href="/about">about</a>`), "index.html")))
+}
+
+func TestExportRedirectPage(t *testing.T) {
+	page := string(exportRedirectPage("/about", "old/index.html"))
+	assert.Contains(t, page, `<meta http-equiv="refresh" content="0; url=../about/index.html">`)
+	page = string(exportRedirectPage("https://example.com/?a=1&b=2", "old/index.html"))
+	assert.Contains(t, page, `url=https://example.com/?a=1&amp;b=2"`)
+}
+
+func TestDirExportWriterRejectsEscapingPaths(t *testing.T) {
+	w := NewDirExportWriter(t.TempDir())
+	assert.NoError(t, w.WriteFile("about/index.html", []byte("ok")))
+	assert.True(t, core.IsInvalid(w.WriteFile("../escape.html", []byte("no"))))
+}
+
+func TestExportBypassesRenderCache(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	s.RenderCache().Set(site.Version, "/", RenderedPage{Body: []byte("cached"), ContentType: fiber.MIMETextHTMLCharsetUTF8})
+
+	dir := t.TempDir()
+	report, err := s.Export(ctx, ExportOptions{BaseURL: "https://export.example"}, NewDirExportWriter(dir))
+	require.NoError(t, err)
+	assert.Empty(t, report.Failures)
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "Welcome to Pagebin")
+	sitemap, err := os.ReadFile(filepath.Join(dir, "sitemap.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(sitemap), "https://export.example/")
+	assert.Equal(t, 1, s.RenderCache().Stats().Entries, "exported output is not cached for the site")
+}
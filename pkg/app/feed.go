@@ -0,0 +1,391 @@
+package app
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	feedTagParam = ":tag"
+
+	contentTypeRSS  = "application/rss+xml; charset=utf-8"
+	contentTypeAtom = "application/atom+xml; charset=utf-8"
+	contentTypeJSON = "application/feed+json; charset=utf-8"
+
+	jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+)
+
+// feedData is a feed to encode in any format. URLs are absolute.
+type feedData struct {
+	Title   string
+	SiteURL string
+	FeedURL string
+	Updated time.Time
+	Items   []feedItem
+}
+
+type feedItem struct {
+	Title     string
+	URL       string
+	Summary   string
+	Content   string
+	Tags      []string
+	Published time.Time
+	Updated   time.Time
+}
+
+// siteFeeds returns the feeds configured for the site, or the default feeds when the site has not configured any.
+func siteFeeds(site core.Site) []core.Feed {
+	if site.Feeds == nil {
+		return core.DefaultFeeds
+	}
+	return site.Feeds
+}
+
+// matchFeed returns the feed served at path, along with the tag captured by a :tag segment.
+func matchFeed(feeds []core.Feed, path string) (core.Feed, string, bool) {
+	for _, feed := range feeds {
+		if tag, ok := matchFeedPath(feed.Path, path); ok {
+			return feed, tag, true
+		}
+	}
+	return core.Feed{}, "", false
+}
+
+func matchFeedPath(pattern string, path string) (string, bool) {
+	patternSegments := strings.Split(pattern, "/")
+	pathSegments := strings.Split(path, "/")
+	if len(patternSegments) != len(pathSegments) {
+		return "", false
+	}
+	tag := ""
+	for i, segment := range patternSegments {
+		switch {
+		case segment == feedTagParam && pathSegments[i] != "":
+			tag = pathSegments[i]
+		case segment != pathSegments[i]:
+			return "", false
+		}
+	}
+	return tag, true
+}
+
+// validateFeeds rejects feeds that cannot be served.
+func validateFeeds(feeds []core.Feed) error {
+	paths := map[string]bool{}
+	for _, feed := range feeds {
+		if !strings.HasPrefix(feed.Path, "/") {
+			return core.ErrInvalidFeed.New("path \"%s\" must start with /", feed.Path)
+		}
+		if isReservedPath(feed.Path) {
+			return core.ErrInvalidFeed.New("path \"%s\" is reserved", feed.Path)
+		}
+		for _, segment := range strings.Split(feed.Path, "/") {
+			if strings.ContainsAny(segment, string(routeParamPrefix)+string(routeWildcardPrefix)) && segment != feedTagParam {
+				return core.ErrInvalidFeed.New("path \"%s\" may only have a %s param", feed.Path, feedTagParam)
+			}
+		}
+		if paths[feed.Path] {
+			return core.ErrInvalidFeed.New("path \"%s\" is used by more than one feed", feed.Path)
+		}
+		paths[feed.Path] = true
+		switch feed.Format {
+		case core.FeedFormatRSS, core.FeedFormatAtom, core.FeedFormatJSON:
+		default:
+			return core.ErrInvalidFeed.New("format must be one of \"%s\", \"%s\" or \"%s\"", core.FeedFormatRSS, core.FeedFormatAtom, core.FeedFormatJSON)
+		}
+		if feed.Limit < 0 {
+			return core.ErrInvalidFeed.New("limit must not be negative")
+		}
+	}
+	return nil
+}
+
+// validateSiteURL rejects a site URL that is not an absolute http or https URL. An empty URL is allowed.
+func validateSiteURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return core.ErrInvalidSite.New("url \"%s\" must be an absolute http or https URL", raw)
+	}
+	return nil
+}
+
+// siteURL is the URL that paths of the site are made absolute with, without a trailing slash. When the site has no
+// URL, it comes from the Host header of the request, so output that uses it is only cached when siteURLCacheable.
+func siteURL(ctx *fiber.Ctx, site core.Site) string {
+	if site.URL != "" {
+		return strings.TrimSuffix(site.URL, "/")
+	}
+	return ctx.BaseURL()
+}
+
+// siteURLCacheable reports whether output with links from siteURL can be cached. The render cache key does not include
+// the host, so links built from the Host header of one request must not be served to others.
+func siteURLCacheable(site core.Site) bool {
+	return site.URL != ""
+}
+
+// feed serves a feed of the target version. A feed for a tag that no page has is not found.
+func (r renderer) feed(ctx *fiber.Ctx, site core.Site, targetVersion *core.TargetVersion, feed core.Feed, tag string, cacheable bool, cacheKey string) error {
+	version, err := r.service.GetVersion(ctx.Context(), targetVersion.UID())
+	if err != nil {
+		return err
+	}
+	query := PageQuery{Tag: feed.Tag, Prefix: feed.Prefix, Sort: SortDate, Limit: feed.Limit}
+	if tag != "" {
+		query.Tag = tag
+	}
+	if query.Limit == 0 {
+		query.Limit = core.DefaultFeedLimit
+	}
+	collection, err := r.service.Collections().Query(ctx.Context(), version.UID, query)
+	if err != nil {
+		return err
+	}
+	if tag != "" && collection.Total == 0 {
+		return core.ErrPageNotFound.New("no pages are tagged \"%s\"", tag)
+	}
+
+	base := siteURL(ctx, site)
+	data := feedData{
+		Title:   feed.Title,
+		SiteURL: base + "/",
+		FeedURL: base + ctx.Path(),
+		Updated: site.UpdatedAt,
+	}
+	if data.Title == "" {
+		data.Title = site.Title
+		if tag != "" {
+			data.Title += ": " + tag
+		}
+	}
+	for i, page := range collection.pages {
+		content, err := r.content(ctx, page, version.Theme)
+		if err != nil {
+			return err
+		}
+		data.Items = append(data.Items, feedItem{
+			Title:     page.Title,
+			URL:       base + page.Path,
+			Summary:   page.Excerpt,
+			Content:   string(content),
+			Tags:      collection.Items[i].Tags,
+			Published: page.CreatedAt,
+			Updated:   page.UpdatedAt,
+		})
+		if i == 0 || page.UpdatedAt.After(data.Updated) {
+			data.Updated = page.UpdatedAt
+		}
+	}
+
+	rendered := RenderedPage{}
+	rendered.Body, rendered.ContentType, err = encodeFeed(feed.Format, data)
+	if err != nil {
+		return err
+	}
+	rendered.ETag = bodyETag(rendered.Body)
+	if targetVersion.IsCurrent() {
+		rendered.LastModified = site.UpdatedAt
+	}
+	if cacheable && siteURLCacheable(site) {
+		r.service.RenderCache().Set(targetVersion.UID(), cacheKey, rendered)
+	}
+	return r.send(ctx, rendered)
+}
+
+// encodeFeed encodes a feed in a format, returning the body and its content type.
+func encodeFeed(format string, data feedData) ([]byte, string, error) {
+	switch format {
+	case core.FeedFormatRSS:
+		body, err := encodeXML(newRSS(data))
+		return body, contentTypeRSS, err
+	case core.FeedFormatAtom:
+		body, err := encodeXML(newAtom(data))
+		return body, contentTypeAtom, err
+	case core.FeedFormatJSON:
+		body, err := json.Marshal(newJSONFeed(data))
+		return body, contentTypeJSON, err
+	}
+	return nil, "", core.ErrInvalidFeed.New("unknown feed format \"%s\"", format)
+}
+
+func encodeXML(v any) ([]byte, error) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+type rss struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description,omitempty"`
+	Content     string   `xml:"content:encoded"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func newRSS(data feedData) rss {
+	channel := rssChannel{
+		Title:         data.Title,
+		Link:          data.SiteURL,
+		Description:   data.Title,
+		Self:          atomLink{Href: data.FeedURL, Rel: "self", Type: strings.Split(contentTypeRSS, ";")[0]},
+		LastBuildDate: data.Updated.UTC().Format(time.RFC1123Z),
+	}
+	for _, item := range data.Items {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			GUID:        rssGUID{IsPermaLink: true, Value: item.URL},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Description: item.Summary,
+			Content:     item.Content,
+			Categories:  item.Tags,
+		})
+	}
+	return rss{
+		Version:   "2.0",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		Channel:   channel,
+	}
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func newAtom(data feedData) atomFeed {
+	feed := atomFeed{
+		Title:   data.Title,
+		ID:      data.FeedURL,
+		Updated: data.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: data.FeedURL, Rel: "self", Type: strings.Split(contentTypeAtom, ";")[0]},
+			{Href: data.SiteURL, Rel: "alternate", Type: "text/html"},
+		},
+	}
+	for _, item := range data.Items {
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.URL,
+			Link:      atomLink{Href: item.URL, Rel: "alternate", Type: "text/html"},
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Summary:   item.Summary,
+			Content:   atomContent{Type: "html", Value: item.Content},
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"`
+	Title         string    `json:"title"`
+	ContentHTML   string    `json:"content_html"`
+	Summary       string    `json:"summary,omitempty"`
+	DatePublished time.Time `json:"date_published"`
+	DateModified  time.Time `json:"date_modified"`
+	Tags          []string  `json:"tags,omitempty"`
+}
+
+func newJSONFeed(data feedData) jsonFeed {
+	feed := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       data.Title,
+		HomePageURL: data.SiteURL,
+		FeedURL:     data.FeedURL,
+		Items:       []jsonFeedItem{},
+	}
+	for _, item := range data.Items {
+		feed.Items = append(feed.Items, jsonFeedItem{
+			ID:            item.URL,
+			URL:           item.URL,
+			Title:         item.Title,
+			ContentHTML:   item.Content,
+			Summary:       item.Summary,
+			DatePublished: item.Published.UTC(),
+			DateModified:  item.Updated.UTC(),
+			Tags:          item.Tags,
+		})
+	}
+	return feed
+}
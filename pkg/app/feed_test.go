@@ -0,0 +1,153 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchFeed(t *testing.T) {
+	feed, tag, ok := matchFeed(core.DefaultFeeds, "/feed.xml")
+	assert.True(t, ok)
+	assert.Equal(t, core.FeedFormatRSS, feed.Format)
+	assert.Empty(t, tag)
+
+	feed, tag, ok = matchFeed(core.DefaultFeeds, "/tags/go/feed.json")
+	assert.True(t, ok)
+	assert.Equal(t, core.FeedFormatJSON, feed.Format)
+	assert.Equal(t, "go", tag)
+
+	for _, path := range []string{"/", "/feed", "/tags//feed.xml", "/tags/go/feed.xml/more", "/blog/feed.xml"} {
+		_, _, ok := matchFeed(core.DefaultFeeds, path)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestValidateFeeds(t *testing.T) {
+	assert.NoError(t, validateFeeds(core.DefaultFeeds))
+	assert.NoError(t, validateFeeds([]core.Feed{}))
+
+	invalid := map[string][]core.Feed{
+		"relative path":  {{Path: "feed.xml", Format: core.FeedFormatRSS}},
+		"reserved path":  {{Path: "/api/feed.xml", Format: core.FeedFormatRSS}},
+		"other param":    {{Path: "/:section/feed.xml", Format: core.FeedFormatRSS}},
+		"wildcard":       {{Path: "/feeds/*", Format: core.FeedFormatRSS}},
+		"unknown format": {{Path: "/feed.xml", Format: "rdf"}},
+		"negative limit": {{Path: "/feed.xml", Format: core.FeedFormatRSS, Limit: -1}},
+		"duplicate path": {{Path: "/feed.xml", Format: core.FeedFormatRSS}, {Path: "/feed.xml", Format: core.FeedFormatAtom}},
+	}
+	for name, feeds := range invalid {
+		assert.True(t, core.IsInvalid(validateFeeds(feeds)), name)
+	}
+}
+
+func testFeedData() feedData {
+	published := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+	return feedData{
+		Title:   "Site",
+		SiteURL: "https://example.com/",
+		FeedURL: "https://example.com/feed.xml",
+		Updated: published.Add(time.Hour),
+		Items: []feedItem{{
+			Title:     "Hello & welcome",
+			URL:       "https://example.com/blog/hello",
+			Summary:   "An excerpt",
+			Content:   "<p>Hello</p>",
+			Tags:      []string{"news"},
+			Published: published,
+			Updated:   published.Add(time.Hour),
+		}},
+	}
+}
+
+func TestEncodeFeedRSS(t *testing.T) {
+	body, contentType, err := encodeFeed(core.FeedFormatRSS, testFeedData())
+	require.NoError(t, err)
+	assert.Equal(t, contentTypeRSS, contentType)
+	out := string(body)
+	assert.Contains(t, out, `<rss version="2.0"`)
+	assert.Contains(t, out, `<atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"></atom:link>`)
+	assert.Contains(t, out, "<title>Hello &amp; welcome</title>")
+	assert.Contains(t, out, `<guid isPermaLink="true">https://example.com/blog/hello</guid>`)
+	assert.Contains(t, out, "<pubDate>Tue, 05 Mar 2024 14:30:00 +0000</pubDate>")
+	assert.Contains(t, out, "<description>An excerpt</description>")
+	assert.Contains(t, out, "<content:encoded>&lt;p&gt;Hello&lt;/p&gt;</content:encoded>")
+	assert.Contains(t, out, "<category>news</category>")
+	assert.NoError(t, xml.Unmarshal(body, new(any)))
+}
+
+func TestEncodeFeedAtom(t *testing.T) {
+	body, contentType, err := encodeFeed(core.FeedFormatAtom, testFeedData())
+	require.NoError(t, err)
+	assert.Equal(t, contentTypeAtom, contentType)
+	out := string(body)
+	assert.Contains(t, out, `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, out, "<updated>2024-03-05T15:30:00Z</updated>")
+	assert.Contains(t, out, "<published>2024-03-05T14:30:00Z</published>")
+	assert.Contains(t, out, `<content type="html">&lt;p&gt;Hello&lt;/p&gt;</content>`)
+	assert.Contains(t, out, `<category term="news"></category>`)
+}
+
+func TestEncodeFeedJSON(t *testing.T) {
+	body, contentType, err := encodeFeed(core.FeedFormatJSON, testFeedData())
+	require.NoError(t, err)
+	assert.Equal(t, contentTypeJSON, contentType)
+	var feed map[string]any
+	require.NoError(t, json.Unmarshal(body, &feed))
+	assert.Equal(t, jsonFeedVersion, feed["version"])
+	assert.Equal(t, "https://example.com/feed.xml", feed["feed_url"])
+	item := feed["items"].([]any)[0].(map[string]any)
+	assert.Equal(t, "<p>Hello</p>", item["content_html"])
+	assert.Equal(t, "2024-03-05T14:30:00Z", item["date_published"])
+	assert.Equal(t, []any{"news"}, item["tags"])
+
+	_, _, err = encodeFeed("rdf", testFeedData())
+	assert.Error(t, err)
+}
+
+func TestRenderFeedPriority(t *testing.T) {
+	s := newTestService(t)
+	target := nextTarget(t, s)
+	putTestPage(t, s, target, nil, "/feed.xml", "a page at the feed path")
+	putTestPage(t, s, target, nil, "/*rest", "a catch-all page")
+	_, err := s.PutPage(context.Background(), target, nil, core.WritablePage{
+		Title:        "Go",
+		Path:         "/go",
+		TemplateName: "default",
+		Tags:         []string{"go"},
+		ContentType:  core.ContentTypeMarkdown,
+	}, []byte("a tagged page"))
+	require.NoError(t, err)
+	publishTest(t, s)
+	app := newApp(s.rc, s, newErrorHandler(s))
+
+	get := func(path string) (*http.Response, string) {
+		res, err := app.Test(httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil))
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+	res, body := get("/feed.xml")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, fiber.MIMETextHTML, res.Header.Get(fiber.HeaderContentType))
+	assert.Contains(t, body, "a page at the feed path")
+	res, body = get("/atom.xml")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get(fiber.HeaderContentType), "atom")
+	assert.NotContains(t, body, "a catch-all page")
+	res, _ = get("/tags/go/feed.json")
+	assert.Contains(t, res.Header.Get(fiber.HeaderContentType), "json")
+	_, body = get("/other")
+	assert.Contains(t, body, "a catch-all page")
+}
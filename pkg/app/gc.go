@@ -0,0 +1,239 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/aarongodin/pagebin/pkg/store"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/oklog/ulid/v2"
+)
+
+// gcOrphanGracePeriod is how long a blob file without a record is kept, so that files written by a transaction that
+// has not committed yet are not deleted.
+const gcOrphanGracePeriod = time.Hour
+
+// garbageCollector deletes versions that fall outside of the retention policy along with every page, theme and blob
+// that is no longer reachable from a retained version.
+type garbageCollector struct {
+	store       store.Store
+	retainCount int
+	retainAge   time.Duration
+}
+
+// collect marks and sweeps in a single write transaction so that it does not interleave with page writes. Blob files
+// are only removed after the transaction commits, along with orphaned files that no blob record points to.
+func (gc garbageCollector) collect(ctx context.Context, dryRun bool) (core.GCReport, error) {
+	report, err := gc.sweep(ctx, dryRun)
+	if err != nil || dryRun {
+		return report, err
+	}
+	for _, uid := range slices.Concat(report.Blobs, report.Orphans) {
+		if err := gc.store.Blobs().DeleteBytes(ctx, uid); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (gc garbageCollector) sweep(ctx context.Context, dryRun bool) (report core.GCReport, txErr error) {
+	ctx, err := gc.store.StartTx(ctx, !dryRun)
+	if err != nil {
+		return report, err
+	}
+	defer func() {
+		txErr = gc.store.EndTx(ctx, txErr)
+	}()
+	report = core.GCReport{
+		DryRun:   dryRun,
+		Versions: []ulid.ULID{},
+		Pages:    []ulid.ULID{},
+		Themes:   []ulid.ULID{},
+		Blobs:    []ulid.ULID{},
+		Orphans:  []ulid.ULID{},
+	}
+
+	retained, err := gc.retainedVersions(ctx)
+	if err != nil {
+		return report, err
+	}
+	versions, err := gc.store.Versions().GetAllVersions(ctx)
+	if err != nil {
+		return report, err
+	}
+	pages := mapset.NewThreadUnsafeSet[ulid.ULID]()
+	themes := mapset.NewThreadUnsafeSet[ulid.ULID]()
+	blobs := mapset.NewThreadUnsafeSet[ulid.ULID]()
+	for _, version := range versions {
+		if !retained.Contains(version.UID) {
+			report.Versions = append(report.Versions, version.UID)
+			continue
+		}
+		for _, pageUID := range version.Pages {
+			if pages.Add(pageUID) {
+				page, err := gc.store.Pages().GetPage(ctx, pageUID)
+				if err != nil {
+					return report, err
+				}
+				blobs.Add(page.Content)
+			}
+		}
+		if themes.Add(version.Theme) {
+			theme, err := gc.store.Themes().GetTheme(ctx, version.Theme)
+			if err != nil {
+				return report, err
+			}
+			for _, uid := range theme.Templates {
+				blobs.Add(uid)
+			}
+			blobs.Append(theme.CSSAssets...)
+			blobs.Append(theme.JSAssets...)
+		}
+	}
+
+	if report.Pages, err = unreachable(ctx, gc.store.Pages().GetPageUIDs, pages); err != nil {
+		return report, err
+	}
+	if report.Themes, err = unreachable(ctx, gc.store.Themes().GetThemeUIDs, themes); err != nil {
+		return report, err
+	}
+	if report.Blobs, err = unreachable(ctx, gc.store.Blobs().GetBlobUIDs, blobs); err != nil {
+		return report, err
+	}
+	if report.Orphans, err = gc.store.Blobs().GetOrphanUIDs(ctx, time.Now().Add(-gcOrphanGracePeriod)); err != nil {
+		return report, err
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	for _, uid := range report.Versions {
+		if err := gc.store.Versions().DeleteVersion(ctx, uid); err != nil {
+			return report, err
+		}
+	}
+	for _, uid := range report.Pages {
+		if err := gc.store.Pages().DeletePage(ctx, uid); err != nil {
+			return report, err
+		}
+		if err := gc.store.Versions().DeletePageIndex(ctx, uid); err != nil {
+			return report, err
+		}
+	}
+	for _, uid := range report.Themes {
+		if err := gc.store.Themes().DeleteTheme(ctx, uid); err != nil {
+			return report, err
+		}
+	}
+	for _, uid := range report.Blobs {
+		if err := gc.store.Blobs().DeleteBlob(ctx, uid); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// retainedVersions returns the current, next and draft versions along with the versions they were cloned from, the
+// versions targeted by pending schedules and unexpired previews, and the versions kept by the retention policy.
+func (gc garbageCollector) retainedVersions(ctx context.Context) (mapset.Set[ulid.ULID], error) {
+	site, err := gc.store.Sites().GetSite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	retained := mapset.NewThreadUnsafeSet(site.Version, site.NextVersion)
+	for _, uid := range site.Drafts {
+		retained.Add(uid)
+	}
+	for _, uid := range retained.ToSlice() {
+		if uid == site.Version {
+			continue
+		}
+		version, err := gc.store.Versions().GetVersion(ctx, uid)
+		if err != nil {
+			return nil, err
+		}
+		if version.Base != (ulid.ULID{}) {
+			retained.Add(version.Base)
+		}
+	}
+
+	schedules, err := gc.store.Schedules().GetPendingSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, schedule := range schedules {
+		retained.Add(schedule.Version)
+	}
+	if err := gc.retainPreviewed(ctx, retained); err != nil {
+		return nil, err
+	}
+
+	versions, err := gc.store.Versions().GetAllVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	published := slices.DeleteFunc(slices.Clone(versions), func(v core.Version) bool {
+		return v.PublishedAt.IsZero()
+	})
+	unpublished := slices.DeleteFunc(versions, func(v core.Version) bool {
+		return !v.PublishedAt.IsZero()
+	})
+	gc.retainRecent(retained, published, func(v core.Version) time.Time { return v.PublishedAt })
+	gc.retainRecent(retained, unpublished, func(v core.Version) time.Time { return v.CreatedAt })
+	return retained, nil
+}
+
+// retainPreviewed adds the versions that unexpired previews target by UID. Previews of the next version or a draft
+// target versions that are always retained.
+func (gc garbageCollector) retainPreviewed(ctx context.Context, retained mapset.Set[ulid.ULID]) error {
+	now := time.Now()
+	var cursor *ulid.ULID
+	for {
+		previews, next, err := gc.store.Previews().GetPreviews(ctx, cursor)
+		if err != nil {
+			return err
+		}
+		for _, preview := range previews {
+			if uid, err := ulid.Parse(preview.Version); err == nil && preview.ExpiresAt.After(now) {
+				retained.Add(uid)
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// retainRecent adds the most recent versions by the time of at, up to the retention count, along with every version
+// whose time is within the retention age.
+func (gc garbageCollector) retainRecent(retained mapset.Set[ulid.ULID], versions []core.Version, at func(core.Version) time.Time) {
+	slices.SortFunc(versions, func(a, b core.Version) int {
+		return at(b).Compare(at(a))
+	})
+	cutoff := time.Now().Add(-gc.retainAge)
+	for i, version := range versions {
+		if i < gc.retainCount || at(version).After(cutoff) {
+			retained.Add(version.UID)
+		}
+	}
+}
+
+func unreachable(ctx context.Context, list func(ctx context.Context) ([]ulid.ULID, error), reachable mapset.Set[ulid.ULID]) ([]ulid.ULID, error) {
+	all, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(all, reachable.ContainsOne), nil
+}
+
+func newGarbageCollector(rc *config.RuntimeConfig, s store.Store) garbageCollector {
+	return garbageCollector{
+		store:       s,
+		retainCount: rc.GCRetainVersions,
+		retainAge:   rc.GCRetainAge,
+	}
+}
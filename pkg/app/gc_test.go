@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publishTest(t *testing.T, s *Svc) core.Site {
+	t.Helper()
+	site, err := s.Publish(context.Background(), "", "")
+	require.NoError(t, err)
+	return site
+}
+
+func TestCollectGarbage(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, func(rc *config.RuntimeConfig) {
+		rc.GCRetainVersions = 0
+		rc.GCRetainAge = 0
+	})
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	provisioned := site.Version
+
+	// the base of a draft is retained after it is no longer current
+	createTestDraft(t, s, "feature")
+	gone := createTestDraft(t, s, "gone")
+	draftPage := putTestPage(t, s, gone, nil, "/gone", "# Gone")
+	_, err = s.DeleteDraft(ctx, "gone")
+	require.NoError(t, err)
+
+	site = publishTest(t, s)
+	previewed := site.Version
+	_, err = s.CreateSchedule(ctx, time.Now().Add(time.Hour), "", "")
+	require.NoError(t, err)
+	scheduled := site.NextVersion
+	publishTest(t, s)
+	site = publishTest(t, s)
+	expired := site.Version
+	site = publishTest(t, s)
+
+	_, err = s.CreatePreview(ctx, previewed.String(), time.Hour, "")
+	require.NoError(t, err)
+	_, err = s.store.Previews().CreatePreview(ctx, expired.String(), time.Now().Add(-time.Minute), "")
+	require.NoError(t, err)
+
+	expected := []ulid.ULID{gone.UID(), expired}
+	report, err := s.CollectGarbage(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.ElementsMatch(t, expected, report.Versions)
+	assert.Equal(t, []ulid.ULID{draftPage.UID}, report.Pages)
+	for _, uid := range expected {
+		_, err := s.GetVersion(ctx, uid)
+		assert.NoError(t, err, "a dry run does not delete anything")
+	}
+
+	report, err = s.CollectGarbage(ctx, false)
+	require.NoError(t, err)
+	assert.False(t, report.DryRun)
+	assert.ElementsMatch(t, expected, report.Versions)
+	assert.Equal(t, []ulid.ULID{draftPage.UID}, report.Pages)
+	for _, uid := range expected {
+		_, err := s.GetVersion(ctx, uid)
+		assert.True(t, errorx.IsNotFound(err), uid.String())
+	}
+	_, err = s.GetPage(ctx, draftPage.UID)
+	assert.True(t, errorx.IsNotFound(err))
+	for _, uid := range []ulid.ULID{provisioned, previewed, scheduled, site.Version, site.NextVersion, site.Drafts["feature"]} {
+		_, err := s.GetVersion(ctx, uid)
+		assert.NoError(t, err, uid.String())
+	}
+
+	report, err = s.CollectGarbage(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Versions)
+	assert.Empty(t, report.Pages)
+	assert.Empty(t, report.Blobs)
+}
+
+func TestCollectGarbageRetainsRecentVersions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, func(rc *config.RuntimeConfig) {
+		rc.GCRetainVersions = 1
+		rc.GCRetainAge = time.Hour
+	})
+	draft := createTestDraft(t, s, "gone")
+	_, err := s.DeleteDraft(ctx, "gone")
+	require.NoError(t, err)
+	publishTest(t, s)
+	publishTest(t, s)
+
+	report, err := s.CollectGarbage(ctx, true)
+	require.NoError(t, err)
+	assert.Empty(t, report.Versions, "versions within the retention age are retained")
+	_, err = s.GetVersion(ctx, draft.UID())
+	assert.NoError(t, err)
+}
+
+func TestCollectGarbageDeletesOrphanedFiles(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	page := putTestPage(t, s, nextTarget(t, s), nil, "/a", "# A")
+	dir := s.rc.BlobLocalFSRootDir
+	old, recent := ulid.Make(), ulid.Make()
+	for _, name := range []string{old.String(), recent.String(), "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("orphan"), 0644))
+	}
+	stale := time.Now().Add(-2 * gcOrphanGracePeriod)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, old.String()), stale, stale))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, page.Content.String()), stale, stale))
+
+	report, err := s.CollectGarbage(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, []ulid.ULID{old}, report.Orphans)
+	assert.FileExists(t, filepath.Join(dir, old.String()), "a dry run does not delete anything")
+
+	report, err = s.CollectGarbage(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, []ulid.ULID{old}, report.Orphans)
+	assert.NoFileExists(t, filepath.Join(dir, old.String()))
+	for _, name := range []string{recent.String(), "notes.txt", page.Content.String()} {
+		assert.FileExists(t, filepath.Join(dir, name), "files within the grace period, not named by a UID or with a record are kept")
+	}
+}
@@ -1,7 +1,9 @@
 package app
 
 import (
+	"context"
 	"strings"
+	"time"
 
 	"github.com/aarongodin/pagebin/pkg/core"
 	"github.com/gofiber/fiber/v2"
@@ -18,21 +20,40 @@ func getTargetVersion(ctx *fiber.Ctx, svc Service, write bool) (*core.TargetVers
 	if len(versionHeader) == 0 {
 		if write {
 			return core.NewNextTargetVersion(site.NextVersion), nil
-		} else {
-			return core.NewCurrentTargetVersion(site.Version), nil
 		}
+		preview, err := getPreview(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		if preview != nil {
+			target, err := resolveTargetVersion(ctx.Context(), svc, site, preview.Version)
+			if err == nil || ctx.Query(QueryPreview) != "" {
+				return target, err
+			}
+			// the previewed draft or version was merged, deleted or collected after the cookie was set
+			clearPreviewCookie(ctx)
+		}
+		return core.NewCurrentTargetVersion(site.Version), nil
 	}
 
 	v := strings.TrimSpace(versionHeader[0])
 	if len(v) == 0 {
-		return nil, core.ErrInvalidVersion.New("%s header invalid. Specify either \"next\" or a version UID", HeaderPagebinVersion)
+		return nil, core.ErrInvalidVersion.New("%s header invalid. Specify either \"next\", a draft name or a version UID", HeaderPagebinVersion)
 	}
+	return resolveTargetVersion(ctx.Context(), svc, site, v)
+}
+
+// resolveTargetVersion resolves "next", a draft name or a version UID to a target version.
+func resolveTargetVersion(ctx context.Context, svc Service, site core.Site, v string) (*core.TargetVersion, error) {
 	if v == "next" {
 		return core.NewNextTargetVersion(site.NextVersion), nil
 	}
+	if uid, exists := site.Drafts[v]; exists {
+		return core.NewDraftTargetVersion(uid, v), nil
+	}
 	parsed, err := ulid.Parse(v)
 	if err != nil {
-		return nil, core.ErrInvalidVersion.New("%s header invalid. Specify either \"next\" or a version UID", HeaderPagebinVersion)
+		return nil, core.ErrInvalidVersion.New("version \"%s\" invalid. Specify either \"next\", a draft name or a version UID", v)
 	}
 	switch {
 	case parsed == site.NextVersion:
@@ -40,9 +61,53 @@ func getTargetVersion(ctx *fiber.Ctx, svc Service, write bool) (*core.TargetVers
 	case parsed == site.Version:
 		return core.NewCurrentTargetVersion(site.Version), nil
 	default:
-		if _, err := svc.GetVersion(ctx.Context(), parsed); err != nil {
+		for name, uid := range site.Drafts {
+			if uid == parsed {
+				return core.NewDraftTargetVersion(uid, name), nil
+			}
+		}
+		if _, err := svc.GetVersion(ctx, parsed); err != nil {
 			return nil, err
 		}
 		return core.NewTargetVersion(parsed), nil
 	}
 }
+
+// getPreview returns the preview for a token given in the query or, failing that, the preview cookie. A valid token
+// from the query is stored in the cookie so that links followed from the previewed page stay in the preview. An
+// invalid cookie is cleared rather than failing the request, as is a cookie for a version that no longer resolves.
+func getPreview(ctx *fiber.Ctx, svc Service) (*core.Preview, error) {
+	if token := ctx.Query(QueryPreview); token != "" {
+		preview, err := svc.ResolvePreview(ctx.Context(), token)
+		if err != nil {
+			return nil, err
+		}
+		ctx.Cookie(&fiber.Cookie{
+			Name:     CookiePreview,
+			Value:    token,
+			Path:     "/",
+			Expires:  preview.ExpiresAt,
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		return &preview, nil
+	}
+	token := ctx.Cookies(CookiePreview)
+	if token == "" {
+		return nil, nil
+	}
+	preview, err := svc.ResolvePreview(ctx.Context(), token)
+	if err != nil {
+		clearPreviewCookie(ctx)
+		return nil, nil
+	}
+	return &preview, nil
+}
+
+func clearPreviewCookie(ctx *fiber.Ctx) {
+	ctx.Cookie(&fiber.Cookie{
+		Name:    CookiePreview,
+		Path:    "/",
+		Expires: time.Unix(0, 0),
+	})
+}
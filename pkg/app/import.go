@@ -0,0 +1,300 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultImportTemplate  = "default"
+	defaultImportBatchSize = 100
+	importSkipUnchanged    = "unchanged"
+)
+
+// importContentTypes maps the extensions of imported files to their content types. Files with other extensions are
+// not imported.
+var importContentTypes = map[string]string{
+	".md":       core.ContentTypeMarkdown,
+	".markdown": core.ContentTypeMarkdown,
+	".html":     core.ContentTypeHTML,
+	".htm":      core.ContentTypeHTML,
+}
+
+type ImportOptions struct {
+	// Prefix is prepended to the paths derived from file names. Paths set in front matter are used as they are.
+	Prefix string
+	// TemplateName is used for pages that have no template in their front matter.
+	TemplateName string
+	// BatchSize is the number of pages written in each transaction.
+	BatchSize int
+}
+
+// frontMatter is the YAML or TOML block at the start of an imported file. Unknown keys are ignored.
+type frontMatter struct {
+	Title    string   `yaml:"title" toml:"title"`
+	Path     string   `yaml:"path" toml:"path"`
+	Template string   `yaml:"template" toml:"template"`
+	Tags     []string `yaml:"tags" toml:"tags"`
+	Excerpt  string   `yaml:"excerpt" toml:"excerpt"`
+	NoIndex  bool     `yaml:"noIndex" toml:"noIndex"`
+}
+
+type importFile struct {
+	name    string
+	page    core.WritablePage
+	content []byte
+}
+
+// Import walks fsys and writes a page into the next version for each Markdown and HTML file. Hidden files and
+// directories are skipped. Pages are matched to existing pages by path, so importing the same files again leaves the
+// version unchanged. Pages are written in batches of one transaction each; when a batch fails, the pages of earlier
+// batches remain imported and the report lists them.
+func (s *Svc) Import(ctx context.Context, fsys fs.FS, options ImportOptions) (core.ImportReport, error) {
+	if options.TemplateName == "" {
+		options.TemplateName = defaultImportTemplate
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultImportBatchSize
+	}
+	site, err := s.GetSite(ctx)
+	if err != nil {
+		return core.ImportReport{}, err
+	}
+	report := core.ImportReport{
+		Version: site.NextVersion,
+		Created: []string{},
+		Updated: []string{},
+		Skipped: []core.ImportSkip{},
+	}
+
+	var files []importFile
+	seen := map[string]string{}
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		contentType, ok := importContentTypes[strings.ToLower(path.Ext(name))]
+		if d.IsDir() || !ok {
+			return nil
+		}
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		f, err := parseImportFile(name, contentType, raw, options)
+		if err == nil {
+			if other, ok := seen[f.page.Path]; ok {
+				err = core.ErrInvalidPath.New("path \"%s\" is also imported from %s", f.page.Path, other)
+			}
+		}
+		if err != nil {
+			report.Skipped = append(report.Skipped, core.ImportSkip{File: name, Path: f.page.Path, Reason: err.Error()})
+			return nil
+		}
+		seen[f.page.Path] = name
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for start := 0; start < len(files); start += options.BatchSize {
+		end := min(start+options.BatchSize, len(files))
+		if err := s.importBatch(ctx, files[start:end], &report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// importBatch writes files into the next version in one transaction. The report is only updated once the transaction
+// is committed, and the version manager is reloaded when it is rolled back, since PutPage updates it as it goes.
+func (s *Svc) importBatch(ctx context.Context, files []importFile, report *core.ImportReport) (txErr error) {
+	site, err := s.GetSite(ctx)
+	if err != nil {
+		return err
+	}
+	target := core.NewNextTargetVersion(site.NextVersion)
+
+	var created, updated []string
+	var skipped []core.ImportSkip
+	txCtx, err := s.store.StartTx(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		txErr = s.store.EndTx(txCtx, txErr)
+		if txErr != nil {
+			if err := s.reload(ctx, site); err != nil {
+				log.Error().Err(err).Msg("failed to reload site after import failed")
+			}
+			return
+		}
+		// PutPage joins this transaction, so its purge ran before the commit
+		s.purgeVersion(target.UID())
+		report.Created = append(report.Created, created...)
+		report.Updated = append(report.Updated, updated...)
+		report.Skipped = append(report.Skipped, skipped...)
+	}()
+
+	version, err := s.GetVersion(txCtx, site.NextVersion)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		var uid *ulid.ULID
+		if existing, ok := version.Pages[f.page.Path]; ok {
+			unchanged, err := s.importUnchanged(txCtx, existing, f)
+			if err != nil {
+				return err
+			}
+			if unchanged {
+				skipped = append(skipped, core.ImportSkip{File: f.name, Path: f.page.Path, Reason: importSkipUnchanged})
+				continue
+			}
+			uid = &existing
+		}
+		if _, err := s.PutPage(txCtx, target, uid, f.page, f.content); err != nil {
+			return errorx.Decorate(err, "failed to import %s", f.name)
+		}
+		if uid == nil {
+			created = append(created, f.page.Path)
+		} else {
+			updated = append(updated, f.page.Path)
+		}
+	}
+	return nil
+}
+
+// importUnchanged reports whether the page uid already has the fields and content of f.
+func (s *Svc) importUnchanged(ctx context.Context, uid ulid.ULID, f importFile) (bool, error) {
+	page, err := s.GetPage(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+	if page.Title != f.page.Title ||
+		page.TemplateName != f.page.TemplateName ||
+		page.Excerpt != f.page.Excerpt ||
+		page.ContentType != f.page.ContentType ||
+		page.NoIndex != f.page.NoIndex ||
+		!slices.Equal(page.Tags, f.page.Tags) {
+		return false, nil
+	}
+	content, err := s.store.Blobs().GetBytes(ctx, page.Content)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(content, f.content), nil
+}
+
+// parseImportFile reads the front matter of a file and derives the fields it does not set: the path from the file
+// name, the title from the base name and the template from options.
+func parseImportFile(name string, contentType string, raw []byte, options ImportOptions) (importFile, error) {
+	f := importFile{name: name}
+	fm, content, err := splitFrontMatter(raw)
+	if err != nil {
+		return f, err
+	}
+	f.content = content
+	f.page = core.WritablePage{
+		Title:        fm.Title,
+		Path:         fm.Path,
+		TemplateName: fm.Template,
+		Tags:         fm.Tags,
+		Excerpt:      fm.Excerpt,
+		ContentType:  contentType,
+		NoIndex:      fm.NoIndex,
+	}
+	if f.page.Path == "" {
+		f.page.Path = importPath(name, options.Prefix)
+	}
+	if f.page.Title == "" {
+		f.page.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	if f.page.TemplateName == "" {
+		f.page.TemplateName = options.TemplateName
+	}
+	if f.page.Tags == nil {
+		f.page.Tags = []string{}
+	}
+	if err := validatePath(f.page.Path); err != nil {
+		return f, err
+	}
+	if err := validateContent(contentType, content); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// importPath derives a page path from a file name, where an index file is the path of its directory.
+func importPath(name string, prefix string) string {
+	p := strings.TrimSuffix(name, path.Ext(name))
+	if path.Base(p) == "index" {
+		p = path.Dir(p)
+	}
+	return path.Join("/", prefix, p)
+}
+
+// splitFrontMatter returns the front matter of raw and the content after it. YAML front matter is delimited by ---
+// lines and TOML by +++ lines. Content without front matter is returned as it is.
+func splitFrontMatter(raw []byte) (frontMatter, []byte, error) {
+	var fm frontMatter
+	var delim string
+	switch {
+	case bytes.HasPrefix(raw, []byte("---\n")), bytes.HasPrefix(raw, []byte("---\r\n")):
+		delim = "---"
+	case bytes.HasPrefix(raw, []byte("+++\n")), bytes.HasPrefix(raw, []byte("+++\r\n")):
+		delim = "+++"
+	default:
+		return fm, raw, nil
+	}
+	rest := raw[bytes.IndexByte(raw, '\n')+1:]
+	offset := 0
+	for offset <= len(rest) {
+		end := bytes.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end]
+		}
+		if string(bytes.TrimRight(line, " \t\r")) == delim {
+			block := rest[:offset]
+			content := []byte{}
+			if end >= 0 {
+				content = rest[offset+end+1:]
+			}
+			var err error
+			if delim == "---" {
+				err = yaml.Unmarshal(block, &fm)
+			} else {
+				err = toml.Unmarshal(block, &fm)
+			}
+			if err != nil {
+				return fm, nil, core.ErrInvalidFrontMatter.Wrap(err, "failed to parse front matter")
+			}
+			return fm, content, nil
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return fm, nil, core.ErrInvalidFrontMatter.New("front matter is not closed with %s", delim)
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportPath(t *testing.T) {
+	testCases := map[string]string{
+		"index.md":          "/",
+		"about.md":          "/about",
+		"blog/index.html":   "/blog",
+		"blog/post-1.md":    "/blog/post-1",
+		"docs/a.b.markdown": "/docs/a.b",
+	}
+	for name, expected := range testCases {
+		assert.Equal(t, expected, importPath(name, ""), name)
+	}
+	assert.Equal(t, "/docs", importPath("index.md", "docs"))
+	assert.Equal(t, "/docs/blog/post-1", importPath("blog/post-1.md", "/docs/"))
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	fm, content, err := splitFrontMatter([]byte("---\ntitle: Hello\ntags: [a, b]\nexcerpt: Hi\ntemplate: post\npath: /hello\nunknown: 1\n---\n# Hello\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, frontMatter{Title: "Hello", Path: "/hello", Template: "post", Tags: []string{"a", "b"}, Excerpt: "Hi"}, fm)
+	assert.Equal(t, "# Hello\n", string(content))
+
+	fm, content, err = splitFrontMatter([]byte("+++\r\ntitle = \"Hello\"\r\ntags = [\"a\"]\r\nnoIndex = true\r\n+++\r\n<p>Hello</p>"))
+	assert.NoError(t, err)
+	assert.Equal(t, frontMatter{Title: "Hello", Tags: []string{"a"}, NoIndex: true}, fm)
+	assert.Equal(t, "<p>Hello</p>", string(content))
+
+	fm, content, err = splitFrontMatter([]byte("---\ntitle: Hello\n---"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello", fm.Title)
+	assert.Empty(t, content)
+
+	_, content, err = splitFrontMatter([]byte("# No front matter\n---\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# No front matter\n---\n", string(content))
+
+	_, _, err = splitFrontMatter([]byte("---\ntitle: Hello\n"))
+	assert.True(t, core.IsInvalid(err))
+	_, _, err = splitFrontMatter([]byte("---\ntitle: [\n---\n"))
+	assert.True(t, core.IsInvalid(err))
+}
+
+func TestParseImportFile(t *testing.T) {
+	options := ImportOptions{Prefix: "/docs", TemplateName: "default"}
+	f, err := parseImportFile("guides/setup.md", core.ContentTypeMarkdown, []byte("# Setup\n"), options)
+	assert.NoError(t, err)
+	assert.Equal(t, core.WritablePage{
+		Title:        "setup",
+		Path:         "/docs/guides/setup",
+		TemplateName: "default",
+		Tags:         []string{},
+		ContentType:  core.ContentTypeMarkdown,
+	}, f.page)
+	assert.Equal(t, "# Setup\n", string(f.content))
+
+	f, err = parseImportFile("setup.md", core.ContentTypeMarkdown, []byte("---\npath: /setup\ntemplate: guide\n---\n"), options)
+	assert.NoError(t, err)
+	assert.Equal(t, "/setup", f.page.Path)
+	assert.Equal(t, "guide", f.page.TemplateName)
+
+	_, err = parseImportFile("setup.md", core.ContentTypeMarkdown, []byte("---\npath: setup\n---\n"), options)
+	assert.True(t, core.IsInvalid(err))
+}
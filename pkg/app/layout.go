@@ -0,0 +1,73 @@
+package app
+
+import (
+	"slices"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/aymerick/raymond"
+)
+
+const (
+	layoutBlocksData = "_layoutBlocks"
+	layoutChainData  = "_layoutChain"
+	maxLayoutDepth   = 8
+)
+
+// registerLayoutHelpers adds the helpers that let a template extend another template of the theme as its layout:
+//
+//	{{#extend "base"}}
+//	  {{#fill "main"}}<h1>{{ page.title }}</h1>{{{ content }}}{{/fill}}
+//	{{/extend}}
+//
+// The layout marks where the filled blocks go with {{#block "main"}}default content{{/block}}. A layout may extend
+// another layout, in which case blocks filled by the innermost template win.
+func (c *compiledTheme) registerLayoutHelpers(tpl *raymond.Template) {
+	tpl.RegisterHelper("extend", c.extendHelper)
+	tpl.RegisterHelper("fill", fillHelper)
+	tpl.RegisterHelper("block", blockHelper)
+}
+
+func (c *compiledTheme) extendHelper(name string, options *raymond.Options) raymond.SafeString {
+	layout, ok := c.templates[name]
+	if !ok {
+		panic(core.ErrThemeTemplateNotFound.New("layout \"%s\" not found for current theme", name))
+	}
+	chain, _ := options.Data(layoutChainData).([]string)
+	if slices.Contains(chain, name) || len(chain) >= maxLayoutDepth {
+		panic(core.ErrThemeTemplateExec.New("layout \"%s\" extends itself", name))
+	}
+	blocks, ok := options.Data(layoutBlocksData).(map[string]string)
+	if !ok {
+		blocks = map[string]string{}
+	}
+	frame := options.NewDataFrame()
+	frame.Set(layoutBlocksData, blocks)
+	frame.Set(layoutChainData, append(slices.Clone(chain), name))
+	// Only the fill blocks are kept from the body of extend; anything else is discarded.
+	options.FnData(frame)
+	out, err := layout.ExecWith(options.Ctx(), frame)
+	if err != nil {
+		panic(err)
+	}
+	return raymond.SafeString(out)
+}
+
+func fillHelper(name string, options *raymond.Options) string {
+	blocks, ok := options.Data(layoutBlocksData).(map[string]string)
+	if !ok {
+		panic(core.ErrThemeTemplateExec.New("fill \"%s\" must be used inside extend", name))
+	}
+	if _, exists := blocks[name]; !exists {
+		blocks[name] = options.Fn()
+	}
+	return ""
+}
+
+func blockHelper(name string, options *raymond.Options) raymond.SafeString {
+	if blocks, ok := options.Data(layoutBlocksData).(map[string]string); ok {
+		if block, exists := blocks[name]; exists {
+			return raymond.SafeString(block)
+		}
+	}
+	return raymond.SafeString(options.Fn())
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"regexp"
+	"slices"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
+)
+
+var (
+	draftNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+	// PropertyMergeConflict holds the core.MergeConflict of an ErrMergeConflict error.
+	PropertyMergeConflict = errorx.RegisterProperty("merge_conflict")
+)
+
+// merge applies the changes made in the source version since it was cloned onto the live version and returns the UID
+// of the resulting version. When the live version is still the version the source was cloned from, the source is
+// returned as is. A page path or page UID changed by both sides, or a theme changed by both sides, is a conflict. A
+// source without a base cannot be merged, since its changes are unknown, and is a conflict as well.
+func (s *Svc) merge(ctx context.Context, liveUID ulid.ULID, sourceUID ulid.ULID, createdBy string) (ulid.ULID, error) {
+	source, err := s.GetVersion(ctx, sourceUID)
+	if err != nil {
+		return ulid.ULID{}, err
+	}
+	if source.Base == liveUID {
+		return source.UID, nil
+	}
+	if source.Base == (ulid.ULID{}) {
+		return ulid.ULID{}, core.ErrMergeConflict.
+			New("version %s has no base version to merge from", source.UID.String()).
+			WithProperty(PropertyMergeConflict, core.MergeConflict{Paths: []string{}, Pages: []ulid.ULID{}})
+	}
+	base, err := s.GetVersion(ctx, source.Base)
+	if err != nil {
+		return ulid.ULID{}, err
+	}
+	live, err := s.GetVersion(ctx, liveUID)
+	if err != nil {
+		return ulid.ULID{}, err
+	}
+	differ := versionDiffer{s.store}
+	ours, err := differ.diff(ctx, base, source)
+	if err != nil {
+		return ulid.ULID{}, err
+	}
+	theirs, err := differ.diff(ctx, base, live)
+	if err != nil {
+		return ulid.ULID{}, err
+	}
+
+	ourPaths, ourPages := touched(ours)
+	theirPaths, theirPages := touched(theirs)
+	conflict := core.MergeConflict{
+		Paths: ourPaths.Intersect(theirPaths).ToSlice(),
+		Pages: ourPages.Intersect(theirPages).ToSlice(),
+		Theme: ours.Theme != nil && theirs.Theme != nil && source.Theme != live.Theme,
+	}
+	if len(conflict.Paths) > 0 || len(conflict.Pages) > 0 || conflict.Theme {
+		slices.Sort(conflict.Paths)
+		slices.SortFunc(conflict.Pages, func(a, b ulid.ULID) int { return a.Compare(b) })
+		return ulid.ULID{}, core.ErrMergeConflict.
+			New("version %s conflicts with version %s", source.UID.String(), live.UID.String()).
+			WithProperty(PropertyMergeConflict, conflict)
+	}
+
+	pages := make(map[string]ulid.ULID, len(live.Pages))
+	for path, uid := range live.Pages {
+		pages[path] = uid
+	}
+	for path := range ourPaths.Iter() {
+		if uid, exists := source.Pages[path]; exists {
+			pages[path] = uid
+		} else {
+			delete(pages, path)
+		}
+	}
+	theme := live.Theme
+	if ours.Theme != nil {
+		theme = source.Theme
+	}
+	merged, err := s.store.Versions().CreateVersion(ctx, pages, theme, createdBy)
+	if err != nil {
+		return ulid.ULID{}, err
+	}
+	redirects := mergeRedirects(base.Redirects, source.Redirects, live.Redirects)
+	if _, err := s.store.Versions().SetRedirects(ctx, merged.UID, redirects); err != nil {
+		return ulid.ULID{}, err
+	}
+	return merged.UID, nil
+}
+
+// touched returns the paths and page UIDs that appear in any page change of the diff.
+func touched(diff core.VersionDiff) (mapset.Set[string], mapset.Set[ulid.ULID]) {
+	paths := mapset.NewThreadUnsafeSet[string]()
+	pages := mapset.NewThreadUnsafeSet[ulid.ULID]()
+	for _, changes := range [][]core.PageChange{diff.Added, diff.Removed, diff.Moved, diff.ContentChanged, diff.MetadataChanged} {
+		for _, change := range changes {
+			for _, path := range []string{change.Path, change.PreviousPath} {
+				if path != "" {
+					paths.Add(path)
+				}
+			}
+			for _, uid := range []ulid.ULID{change.UID, change.PreviousUID} {
+				if uid != (ulid.ULID{}) {
+					pages.Add(uid)
+				}
+			}
+		}
+	}
+	return paths, pages
+}
+
+func validateDraftName(name string) error {
+	if !draftNamePattern.MatchString(name) {
+		return core.ErrInvalidDraft.New("draft name \"%s\" must be lowercase letters, digits, '-' or '_'", name)
+	}
+	if name == "next" || name == "current" {
+		return core.ErrInvalidDraft.New("draft name \"%s\" is reserved", name)
+	}
+	if _, err := ulid.Parse(name); err == nil {
+		return core.ErrInvalidDraft.New("draft name \"%s\" must not be a version UID", name)
+	}
+	return nil
+}
+
+// CreateDraft forks a named draft from the current version.
+func (s *Svc) CreateDraft(ctx context.Context, name string, createdBy string) (site core.Site, txErr error) {
+	if err := validateDraftName(name); err != nil {
+		return site, err
+	}
+	ctx, err := s.store.StartTx(ctx, true)
+	if err != nil {
+		return site, err
+	}
+	defer func() {
+		txErr = s.store.EndTx(ctx, txErr)
+	}()
+	current, err := s.GetSite(ctx)
+	if err != nil {
+		return site, err
+	}
+	if _, exists := current.Drafts[name]; exists {
+		return site, core.ErrInvalidDraft.New("draft \"%s\" already exists", name)
+	}
+	version, err := s.store.Versions().Clone(ctx, current.Version, createdBy)
+	if err != nil {
+		return site, err
+	}
+	return s.store.Sites().SetDraft(ctx, name, version.UID)
+}
+
+// DeleteDraft discards a draft. Its version is left for garbage collection.
+func (s *Svc) DeleteDraft(ctx context.Context, name string) (core.Site, error) {
+	return s.store.Sites().DeleteDraft(ctx, name)
+}
+
+// MergeDraft merges a draft into the current version and deletes the draft. The next version is left untouched and
+// picks up the merged changes when it is published.
+func (s *Svc) MergeDraft(ctx context.Context, name string, publishedBy string, message string) (core.Site, error) {
+	site, err := s.mergeDraft(ctx, name, publishedBy, message)
+	if err != nil {
+		return core.Site{}, err
+	}
+	if err := s.reload(ctx, site); err != nil {
+		return core.Site{}, err
+	}
+	return site, nil
+}
+
+func (s *Svc) mergeDraft(ctx context.Context, name string, publishedBy string, message string) (site core.Site, txErr error) {
+	ctx, err := s.store.StartTx(ctx, true)
+	if err != nil {
+		return site, err
+	}
+	defer func() {
+		txErr = s.store.EndTx(ctx, txErr)
+	}()
+	current, err := s.GetSite(ctx)
+	if err != nil {
+		return site, err
+	}
+	draft, exists := current.Drafts[name]
+	if !exists {
+		return site, core.ErrDraftNotFound.New("draft \"%s\" not found", name)
+	}
+	merged, err := s.merge(ctx, current.Version, draft, publishedBy)
+	if err != nil {
+		return site, err
+	}
+	if _, err := s.store.Versions().MarkPublished(ctx, merged, publishedBy, message); err != nil {
+		return site, err
+	}
+	if _, err := s.store.Releases().CreateRelease(ctx, core.ReleaseKindMerge, merged, current.Version); err != nil {
+		return site, err
+	}
+	if _, err := s.store.Sites().SetVersions(ctx, merged, current.NextVersion); err != nil {
+		return site, err
+	}
+	return s.store.Sites().DeleteDraft(ctx, name)
+}
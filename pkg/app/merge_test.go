@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestDraft(t *testing.T, s *Svc, name string) *core.TargetVersion {
+	t.Helper()
+	site, err := s.CreateDraft(context.Background(), name, "")
+	require.NoError(t, err)
+	return core.NewDraftTargetVersion(site.Drafts[name], name)
+}
+
+func versionPaths(t *testing.T, s *Svc, uid ulid.ULID) []string {
+	t.Helper()
+	version, err := s.GetVersion(context.Background(), uid)
+	require.NoError(t, err)
+	paths := make([]string, 0, len(version.Pages))
+	for path := range version.Pages {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func TestMergeDraft(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	draft := createTestDraft(t, s, "feature")
+	putTestPage(t, s, draft, nil, "/draft", "# Draft")
+	redirect, err := s.CreateRedirect(ctx, draft, core.Redirect{Kind: core.RedirectKindExact, From: "/d", To: "/draft"})
+	require.NoError(t, err)
+	putTestPage(t, s, nextTarget(t, s), nil, "/next", "# Next")
+	_, err = s.Publish(ctx, "", "")
+	require.NoError(t, err)
+
+	site, err := s.MergeDraft(ctx, "feature", "", "")
+	require.NoError(t, err)
+	assert.NotContains(t, site.Drafts, "feature")
+	assert.Equal(t, []string{"/", "/draft", "/next"}, versionPaths(t, s, site.Version))
+	current, err := s.GetVersion(ctx, site.Version)
+	require.NoError(t, err)
+	require.Len(t, current.Redirects, 1)
+	assert.Equal(t, redirect.UID, current.Redirects[0].UID)
+
+	site, err = s.Publish(ctx, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/", "/draft", "/next"}, versionPaths(t, s, site.Version),
+		"publishing the next version keeps the changes merged into the current version")
+}
+
+func TestMergeDraftConflict(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	current, err := s.GetVersion(ctx, site.Version)
+	require.NoError(t, err)
+	home := current.Pages["/"]
+
+	draft := createTestDraft(t, s, "feature")
+	putTestPage(t, s, draft, &home, "/", "# Draft home")
+	putTestPage(t, s, draft, nil, "/draft", "# Draft")
+	putTestPage(t, s, nextTarget(t, s), &home, "/", "# Next home")
+	_, err = s.Publish(ctx, "", "")
+	require.NoError(t, err)
+
+	_, err = s.MergeDraft(ctx, "feature", "", "")
+	require.True(t, errorx.IsOfType(err, core.ErrMergeConflict), err)
+	property, ok := errorx.ExtractProperty(err, PropertyMergeConflict)
+	require.True(t, ok)
+	conflict := property.(core.MergeConflict)
+	assert.Equal(t, []string{"/"}, conflict.Paths)
+	assert.Contains(t, conflict.Pages, home)
+	assert.False(t, conflict.Theme)
+
+	site, err = s.GetSite(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, site.Drafts, "feature", "a conflicting draft is kept")
+	assert.Equal(t, []string{"/"}, versionPaths(t, s, site.Version))
+}
+
+func TestMergeWithoutBase(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	current, err := s.GetVersion(ctx, site.Version)
+	require.NoError(t, err)
+	version, err := s.store.Versions().CreateVersion(ctx, current.Pages, current.Theme, "")
+	require.NoError(t, err)
+
+	_, err = s.merge(ctx, site.Version, version.UID, "")
+	assert.True(t, errorx.IsOfType(err, core.ErrMergeConflict), err)
+}
+
+func TestProvisionBackfillsBase(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	_, err = s.store.Versions().SetBase(ctx, site.NextVersion, ulid.ULID{})
+	require.NoError(t, err)
+
+	require.NoError(t, Provision(ctx, s.store))
+	next, err := s.GetVersion(ctx, site.NextVersion)
+	require.NoError(t, err)
+	assert.Equal(t, site.Version, next.Base)
+}
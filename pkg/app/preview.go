@@ -0,0 +1,78 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	QueryPreview  = "preview"
+	CookiePreview = "pagebin_preview"
+)
+
+// previewSigner creates and verifies preview tokens. A token is the preview UID, target version and expiry joined by
+// dots, followed by an HMAC-SHA256 signature of those fields.
+type previewSigner struct {
+	secret []byte
+}
+
+func (s previewSigner) sign(preview core.Preview) string {
+	payload := strings.Join([]string{
+		preview.UID.String(),
+		preview.Version,
+		strconv.FormatInt(preview.ExpiresAt.Unix(), 10),
+	}, ".")
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// verify checks the signature and expiry of a token and returns the preview UID it was issued for.
+func (s previewSigner) verify(token string) (ulid.ULID, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return ulid.ULID{}, core.ErrInvalidPreview.New("malformed preview token")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil || !hmac.Equal(sig, s.mac(strings.Join(parts[:3], "."))) {
+		return ulid.ULID{}, core.ErrInvalidPreview.New("invalid preview token signature")
+	}
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return ulid.ULID{}, core.ErrInvalidPreview.New("malformed preview token")
+	}
+	if time.Now().Unix() >= expiresAt {
+		return ulid.ULID{}, core.ErrInvalidPreview.New("preview token expired")
+	}
+	uid, err := ulid.Parse(parts[0])
+	if err != nil {
+		return ulid.ULID{}, core.ErrInvalidPreview.Wrap(err, "malformed preview token")
+	}
+	return uid, nil
+}
+
+func (s previewSigner) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+func newPreviewSigner(rc *config.RuntimeConfig) (previewSigner, error) {
+	if rc.PreviewSecret != "" {
+		return previewSigner{[]byte(rc.PreviewSecret)}, nil
+	}
+	log.Warn().Msg("PREVIEW_SECRET is not set; preview tokens will stop working after a restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return previewSigner{}, err
+	}
+	return previewSigner{secret}, nil
+}
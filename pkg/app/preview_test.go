@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPreview(expiresAt time.Time) core.Preview {
+	return core.Preview{UID: ulid.Make(), Version: "next", ExpiresAt: expiresAt}
+}
+
+// signPayload signs fields the same way as previewSigner.sign, so that tests can build validly signed tokens with
+// malformed fields.
+func signPayload(s previewSigner, fields ...string) string {
+	payload := strings.Join(fields, ".")
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+func TestPreviewSignerVerify(t *testing.T) {
+	s := previewSigner{[]byte("secret")}
+	preview := testPreview(time.Now().Add(time.Hour))
+	uid, err := s.verify(s.sign(preview))
+	require.NoError(t, err)
+	assert.Equal(t, preview.UID, uid)
+}
+
+func TestPreviewSignerRejectsInvalidTokens(t *testing.T) {
+	s := previewSigner{[]byte("secret")}
+	preview := testPreview(time.Now().Add(time.Hour))
+	token := s.sign(preview)
+	parts := strings.Split(token, ".")
+	expires := parts[2]
+
+	testCases := map[string]string{
+		"tampered version":     strings.Join([]string{parts[0], "other", parts[2], parts[3]}, "."),
+		"tampered expiry":      strings.Join([]string{parts[0], parts[1], "9999999999", parts[3]}, "."),
+		"tampered uid":         strings.Join([]string{ulid.Make().String(), parts[1], parts[2], parts[3]}, "."),
+		"bad signature":        strings.Join(append(parts[:3:3], base64.RawURLEncoding.EncodeToString([]byte("nope"))), "."),
+		"signature not base64": strings.Join(append(parts[:3:3], "!!"), "."),
+		"other secret":         previewSigner{[]byte("other")}.sign(preview),
+		"expired":              s.sign(testPreview(time.Now().Add(-time.Second))),
+		"empty":                "",
+		"too few parts":        strings.Join(parts[:3], "."),
+		"too many parts":       token + ".x",
+		"expiry not a number":  signPayload(s, parts[0], parts[1], "soon"),
+		"uid not a ULID":       signPayload(s, "not-a-ulid", parts[1], expires),
+	}
+	for desc, token := range testCases {
+		_, err := s.verify(token)
+		assert.True(t, core.IsInvalid(err), desc)
+	}
+}
+
+func TestResolvePreview(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	preview, err := s.CreatePreview(ctx, "next", time.Hour, "")
+	require.NoError(t, err)
+
+	resolved, err := s.ResolvePreview(ctx, preview.Token)
+	require.NoError(t, err)
+	assert.Equal(t, preview.UID, resolved.UID)
+	assert.Equal(t, "next", resolved.Version)
+
+	require.NoError(t, s.DeletePreview(ctx, preview.UID))
+	_, err = s.ResolvePreview(ctx, preview.Token)
+	assert.True(t, core.IsInvalid(err), "a deleted preview is revoked")
+}
+
+func TestPreviewCookieForRemovedDraft(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	draft := createTestDraft(t, s, "feature")
+	putTestPage(t, s, draft, nil, "/draft", "# Draft")
+	preview, err := s.CreatePreview(ctx, "feature", time.Hour, "")
+	require.NoError(t, err)
+	app := newApp(s.rc, s, newErrorHandler(s))
+	get := func(path string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: CookiePreview, Value: preview.Token})
+		res, err := app.Test(req)
+		require.NoError(t, err)
+		return res
+	}
+
+	res := get("/draft")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get(fiber.HeaderSetCookie))
+
+	_, err = s.DeleteDraft(ctx, "feature")
+	require.NoError(t, err)
+	res = get("/")
+	assert.Equal(t, http.StatusOK, res.StatusCode, "the current version is served instead")
+	assert.Contains(t, res.Header.Get(fiber.HeaderSetCookie), CookiePreview+"=;", "the cookie is cleared")
+	assert.Equal(t, http.StatusNotFound, get("/draft").StatusCode)
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"time"
 
 	"github.com/aarongodin/pagebin/pkg/core"
 	"github.com/aarongodin/pagebin/pkg/store"
@@ -29,7 +30,7 @@ var defaultPage = `
 // Provision adds entities to the DB that are the minimum required for pagebin to function, such as a site, a theme, and a page.
 func Provision(ctx context.Context, store store.Store) error {
 	shouldProvision := false
-	_, err := store.Sites().GetSite(ctx)
+	site, err := store.Sites().GetSite(ctx)
 	if err != nil {
 		if errorx.IsNotFound(err) {
 			shouldProvision = true
@@ -38,7 +39,7 @@ func Provision(ctx context.Context, store store.Store) error {
 		}
 	}
 	if !shouldProvision {
-		return nil
+		return backfillBase(ctx, store, site)
 	}
 	// TOOD: start tx here that goes across all provisioning
 	pageBlob, err := store.Blobs().CreateBlob(ctx, []byte(defaultPage))
@@ -49,7 +50,7 @@ func Provision(ctx context.Context, store store.Store) error {
 		Title:        "Home",
 		Path:         "/",
 		TemplateName: "default",
-	}, pageBlob.UID)
+	}, pageBlob.UID, time.Time{})
 	if err != nil {
 		return err
 	}
@@ -60,18 +61,21 @@ func Provision(ctx context.Context, store store.Store) error {
 	templates := map[string]ulid.ULID{
 		"default": templateBlob.UID,
 	}
-	theme, err := store.Themes().CreateTheme(ctx, templates, nil, nil)
+	theme, err := store.Themes().CreateTheme(ctx, templates, nil, nil, core.ThemeAssetOptions{})
 	if err != nil {
 		return err
 	}
 	pages := map[string]ulid.ULID{
 		page.Path: page.UID,
 	}
-	version, err := store.Versions().CreateVersion(ctx, pages, theme.UID)
+	version, err := store.Versions().CreateVersion(ctx, pages, theme.UID, "")
 	if err != nil {
 		return err
 	}
-	nextVersion, err := store.Versions().Clone(ctx, version.UID)
+	if _, err := store.Versions().MarkPublished(ctx, version.UID, "", ""); err != nil {
+		return err
+	}
+	nextVersion, err := store.Versions().Clone(ctx, version.UID, "")
 	if err != nil {
 		return err
 	}
@@ -80,3 +84,17 @@ func Provision(ctx context.Context, store store.Store) error {
 	}
 	return nil
 }
+
+// backfillBase sets the base of a next version that was cloned before versions recorded the version they were cloned
+// from. The next version is always cloned from the current version, which is the base a publish merges against.
+func backfillBase(ctx context.Context, store store.Store, site core.Site) error {
+	next, err := store.Versions().GetVersion(ctx, site.NextVersion)
+	if err != nil {
+		return err
+	}
+	if next.Base != (ulid.ULID{}) {
+		return nil
+	}
+	_, err = store.Versions().SetBase(ctx, next.UID, site.Version)
+	return err
+}
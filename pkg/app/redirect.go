@@ -0,0 +1,239 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/oklog/ulid/v2"
+)
+
+var redirectStatuses = [...]int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+// compiledRedirects matches request paths against the redirects of a version. Exact redirects are checked first, then
+// prefix redirects from the longest prefix, then regex redirects in the order they were added.
+type compiledRedirects struct {
+	exact    map[string]core.Redirect
+	prefixes []core.Redirect
+	regexes  []compiledRegexRedirect
+}
+
+type compiledRegexRedirect struct {
+	redirect core.Redirect
+	pattern  *regexp.Regexp
+}
+
+func compileRedirects(redirects []core.Redirect) (*compiledRedirects, error) {
+	c := &compiledRedirects{
+		exact: make(map[string]core.Redirect),
+	}
+	for _, r := range redirects {
+		switch r.Kind {
+		case core.RedirectKindExact:
+			c.exact[r.From] = r
+		case core.RedirectKindPrefix:
+			c.prefixes = append(c.prefixes, r)
+		case core.RedirectKindRegex:
+			pattern, err := regexp.Compile(r.From)
+			if err != nil {
+				return nil, core.ErrInvalidRedirect.Wrap(err, "redirect %s has an invalid pattern", r.UID.String())
+			}
+			c.regexes = append(c.regexes, compiledRegexRedirect{r, pattern})
+		}
+	}
+	slices.SortStableFunc(c.prefixes, func(a, b core.Redirect) int {
+		return len(b.From) - len(a.From)
+	})
+	return c, nil
+}
+
+// match returns the location and status code to redirect a request path to.
+func (c *compiledRedirects) match(path string) (string, int, bool) {
+	if c == nil {
+		return "", 0, false
+	}
+	if r, exists := c.exact[path]; exists {
+		return r.To, r.Status, true
+	}
+	for _, r := range c.prefixes {
+		if hasPathPrefix(path, r.From) {
+			return r.To + path[len(r.From):], r.Status, true
+		}
+	}
+	for _, r := range c.regexes {
+		if match := r.pattern.FindStringSubmatchIndex(path); match != nil {
+			return string(r.pattern.ExpandString(nil, r.redirect.To, path, match)), r.redirect.Status, true
+		}
+	}
+	return "", 0, false
+}
+
+// hasPathPrefix reports whether path is prefix or is under it. The prefix has to end at a segment of path, so that /docs
+// matches /docs/intro but not /docsxyz.
+func hasPathPrefix(path string, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+func validateRedirect(r core.Redirect) error {
+	switch r.Kind {
+	case core.RedirectKindExact, core.RedirectKindPrefix:
+		if !strings.HasPrefix(r.From, "/") {
+			return core.ErrInvalidRedirect.New("from \"%s\" must start with /", r.From)
+		}
+	case core.RedirectKindRegex:
+		if _, err := regexp.Compile(r.From); err != nil {
+			return core.ErrInvalidRedirect.Wrap(err, "from \"%s\" is not a valid regex", r.From)
+		}
+	default:
+		return core.ErrInvalidRedirect.New("kind must be one of \"%s\", \"%s\" or \"%s\"", core.RedirectKindExact, core.RedirectKindPrefix, core.RedirectKindRegex)
+	}
+	if r.To == "" {
+		return core.ErrInvalidRedirect.New("to is required")
+	}
+	if !slices.Contains(redirectStatuses[:], r.Status) {
+		return core.ErrInvalidRedirect.New("status must be one of 301, 302, 307 or 308")
+	}
+	return nil
+}
+
+func (s *Svc) GetRedirects(ctx context.Context, target *core.TargetVersion) ([]core.Redirect, error) {
+	version, err := s.GetVersion(ctx, target.UID())
+	if err != nil {
+		return nil, err
+	}
+	if version.Redirects == nil {
+		return []core.Redirect{}, nil
+	}
+	return version.Redirects, nil
+}
+
+// CreateRedirect adds a redirect to the target version, which must be the next version or a draft. A zero status
+// defaults to 301.
+func (s *Svc) CreateRedirect(ctx context.Context, target *core.TargetVersion, redirect core.Redirect) (created core.Redirect, txErr error) {
+	if !target.IsWritable() {
+		return created, core.ErrInvalidVersion.New("version %s is not writable", target.UID().String())
+	}
+	if redirect.Status == 0 {
+		redirect.Status = http.StatusMovedPermanently
+	}
+	redirect.UID = ulid.Make()
+	if err := validateRedirect(redirect); err != nil {
+		return created, err
+	}
+	ctx, err := s.store.StartTx(ctx, true)
+	if err != nil {
+		return created, err
+	}
+	defer func() {
+		if txErr = s.store.EndTx(ctx, txErr); txErr == nil {
+			s.purgeVersion(target.UID())
+		}
+	}()
+	version, err := s.GetVersion(ctx, target.UID())
+	if err != nil {
+		return created, err
+	}
+	if err := s.setRedirects(ctx, target, append(version.Redirects, redirect)); err != nil {
+		return created, err
+	}
+	return redirect, nil
+}
+
+func (s *Svc) DeleteRedirect(ctx context.Context, target *core.TargetVersion, uid ulid.ULID) (txErr error) {
+	if !target.IsWritable() {
+		return core.ErrInvalidVersion.New("version %s is not writable", target.UID().String())
+	}
+	ctx, err := s.store.StartTx(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if txErr = s.store.EndTx(ctx, txErr); txErr == nil {
+			s.purgeVersion(target.UID())
+		}
+	}()
+	version, err := s.GetVersion(ctx, target.UID())
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(version.Redirects, func(r core.Redirect) bool {
+		return r.UID == uid
+	})
+	if i < 0 {
+		return core.ErrRedirectNotFound.New("redirect %s not found", uid.String())
+	}
+	return s.setRedirects(ctx, target, slices.Delete(version.Redirects, i, i+1))
+}
+
+// redirectPage updates the exact redirects of a page that was written to path. Redirects from path are dropped, since
+// they are matched before pages and would shadow the page, and this also keeps moving a page back from creating a loop.
+// When the page moved, a redirect from its previous path is added. Routes with params or wildcards are not redirected
+// since the new path cannot be derived from a request.
+func (s *Svc) redirectPage(ctx context.Context, target *core.TargetVersion, previousPath string, path string) error {
+	version, err := s.GetVersion(ctx, target.UID())
+	if err != nil {
+		return err
+	}
+	moved := previousPath != "" && previousPath != path && staticEnd(previousPath) == len(previousPath) && staticEnd(path) == len(path)
+	count := len(version.Redirects)
+	redirects := slices.DeleteFunc(version.Redirects, func(r core.Redirect) bool {
+		return r.Kind == core.RedirectKindExact && (r.From == path || moved && r.From == previousPath)
+	})
+	if !moved {
+		if len(redirects) == count {
+			return nil
+		}
+		return s.setRedirects(ctx, target, redirects)
+	}
+	redirects = append(redirects, core.Redirect{
+		UID:    ulid.Make(),
+		Kind:   core.RedirectKindExact,
+		From:   previousPath,
+		To:     path,
+		Status: http.StatusMovedPermanently,
+	})
+	return s.setRedirects(ctx, target, redirects)
+}
+
+// setRedirects stores the redirects of the target version. Callers purge the caches of the version once their
+// transaction commits.
+func (s *Svc) setRedirects(ctx context.Context, target *core.TargetVersion, redirects []core.Redirect) error {
+	if _, err := s.store.Versions().SetRedirects(ctx, target.UID(), redirects); err != nil {
+		return err
+	}
+	if target.IsNext() {
+		return s.VersionManager().SetRedirects(redirects)
+	}
+	return nil
+}
+
+// mergeRedirects keeps the redirects of the live version and applies the redirects the source added or removed since
+// the base version.
+func mergeRedirects(base []core.Redirect, source []core.Redirect, live []core.Redirect) []core.Redirect {
+	uids := func(redirects []core.Redirect) mapset.Set[ulid.ULID] {
+		set := mapset.NewThreadUnsafeSet[ulid.ULID]()
+		for _, r := range redirects {
+			set.Add(r.UID)
+		}
+		return set
+	}
+	baseUIDs, sourceUIDs, liveUIDs := uids(base), uids(source), uids(live)
+	merged := slices.DeleteFunc(slices.Clone(live), func(r core.Redirect) bool {
+		return baseUIDs.Contains(r.UID) && !sourceUIDs.Contains(r.UID)
+	})
+	for _, r := range source {
+		if !baseUIDs.Contains(r.UID) && !liveUIDs.Contains(r.UID) {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
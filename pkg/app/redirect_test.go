@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRedirect(kind string, from string, to string) core.Redirect {
+	return core.Redirect{UID: ulid.Make(), Kind: kind, From: from, To: to, Status: http.StatusMovedPermanently}
+}
+
+func TestCompileRedirects(t *testing.T) {
+	c, err := compileRedirects([]core.Redirect{
+		testRedirect(core.RedirectKindPrefix, "/a", "/short"),
+		testRedirect(core.RedirectKindExact, "/old", "/new"),
+		testRedirect(core.RedirectKindPrefix, "/a/b", "/long"),
+		testRedirect(core.RedirectKindRegex, `^/posts/(\d+)$`, "/blog/$1"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/new", c.exact["/old"].To)
+	require.Len(t, c.prefixes, 2)
+	assert.Equal(t, "/a/b", c.prefixes[0].From, "longer prefixes are matched first")
+	require.Len(t, c.regexes, 1)
+
+	_, err = compileRedirects([]core.Redirect{testRedirect(core.RedirectKindRegex, "(", "/x")})
+	assert.True(t, core.IsInvalid(err))
+}
+
+func TestCompiledRedirectsMatch(t *testing.T) {
+	exact := testRedirect(core.RedirectKindExact, "/docs/intro", "/guide")
+	exact.Status = http.StatusFound
+	c, err := compileRedirects([]core.Redirect{
+		testRedirect(core.RedirectKindRegex, `^/docs/(.*)$`, "/regex/$1"),
+		testRedirect(core.RedirectKindPrefix, "/docs", "/manual"),
+		testRedirect(core.RedirectKindPrefix, "/docs/v1/", "/archive/"),
+		exact,
+		testRedirect(core.RedirectKindRegex, `^/posts/(\d+)$`, "/blog/$1"),
+	})
+	require.NoError(t, err)
+	testCases := []struct {
+		path     string
+		location string
+		status   int
+		ok       bool
+	}{
+		{"/docs/intro", "/guide", http.StatusFound, true},
+		{"/docs/setup", "/manual/setup", http.StatusMovedPermanently, true},
+		{"/docs/v1/setup", "/archive/setup", http.StatusMovedPermanently, true},
+		{"/docs", "/manual", http.StatusMovedPermanently, true},
+		{"/docsxyz", "", 0, false},
+		{"/posts/12", "/blog/12", http.StatusMovedPermanently, true},
+		{"/posts/latest", "", 0, false},
+		{"/about", "", 0, false},
+	}
+	for _, tc := range testCases {
+		location, status, ok := c.match(tc.path)
+		assert.Equal(t, tc.ok, ok, tc.path)
+		assert.Equal(t, tc.location, location, tc.path)
+		assert.Equal(t, tc.status, status, tc.path)
+	}
+
+	var none *compiledRedirects
+	_, _, ok := none.match("/docs")
+	assert.False(t, ok)
+}
+
+func TestMergeRedirects(t *testing.T) {
+	kept := testRedirect(core.RedirectKindExact, "/kept", "/a")
+	removedBySource := testRedirect(core.RedirectKindExact, "/removed", "/b")
+	removedByLive := testRedirect(core.RedirectKindExact, "/gone", "/c")
+	addedBySource := testRedirect(core.RedirectKindExact, "/source", "/d")
+	addedByLive := testRedirect(core.RedirectKindExact, "/live", "/e")
+
+	base := []core.Redirect{kept, removedBySource, removedByLive}
+	source := []core.Redirect{kept, removedByLive, addedBySource}
+	live := []core.Redirect{kept, removedBySource, addedByLive}
+	assert.Equal(t, []core.Redirect{kept, addedByLive, addedBySource}, mergeRedirects(base, source, live))
+	assert.Equal(t, []core.Redirect{kept, removedBySource, removedByLive}, base, "inputs are not modified")
+	assert.Equal(t, []core.Redirect{kept, removedBySource, addedByLive}, live, "inputs are not modified")
+}
+
+func TestPutPageRedirects(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	target := nextTarget(t, s)
+
+	page := putTestPage(t, s, target, nil, "/old", "# Page")
+	putTestPage(t, s, target, &page.UID, "/new", "# Page")
+	redirects, err := s.GetRedirects(ctx, target)
+	require.NoError(t, err)
+	require.Len(t, redirects, 1)
+	assert.Equal(t, "/old", redirects[0].From)
+	assert.Equal(t, "/new", redirects[0].To)
+
+	putTestPage(t, s, target, nil, "/old", "# Another page")
+	redirects, err = s.GetRedirects(ctx, target)
+	require.NoError(t, err)
+	assert.Empty(t, redirects, "a page created at the path of a redirect replaces it")
+	_, _, ok, err := s.VersionManager().GetRedirect(ctx, target, "/old")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRedirectWritesPurgeCaches(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	target := nextTarget(t, s)
+	cached := RenderedPage{Body: []byte("cached")}
+
+	s.RenderCache().Set(target.UID(), "/old", cached)
+	redirect, err := s.CreateRedirect(ctx, target, testRedirect(core.RedirectKindExact, "/old", "/"))
+	require.NoError(t, err)
+	_, ok := s.RenderCache().Get(target.UID(), "/old")
+	assert.False(t, ok)
+
+	s.RenderCache().Set(target.UID(), "/old", cached)
+	assert.Error(t, s.DeleteRedirect(ctx, target, ulid.Make()))
+	_, ok = s.RenderCache().Get(target.UID(), "/old")
+	assert.True(t, ok, "a failed write keeps the cache")
+	require.NoError(t, s.DeleteRedirect(ctx, target, redirect.UID))
+	_, ok = s.RenderCache().Get(target.UID(), "/old")
+	assert.False(t, ok)
+}
@@ -1,12 +1,20 @@
 package app
 
 import (
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/config"
 	"github.com/aarongodin/pagebin/pkg/core"
-	"github.com/aymerick/raymond"
 	"github.com/gofiber/fiber/v2"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
 )
 
 type renderer struct {
+	rc      *config.RuntimeConfig
 	service Service
 }
 
@@ -19,8 +27,48 @@ func (r renderer) render(ctx *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	// Only the current version is cached. Requests for other versions are previews and always render fresh output.
+	cacheable := targetVersion.IsCurrent() && len(ctx.Get(HeaderPagebinVersion)) == 0
+	if cacheable {
+		ctx.Set(fiber.HeaderCacheControl, r.rc.CacheControlPages)
+	} else {
+		ctx.Set(fiber.HeaderCacheControl, r.rc.CacheControlPreviews)
+	}
+	cacheKey := renderCacheKey(ctx)
+	if cacheable {
+		if rendered, ok := r.service.RenderCache().Get(targetVersion.UID(), cacheKey); ok {
+			return r.send(ctx, rendered)
+		}
+	}
 
-	pageUID, err := r.service.VersionManager().GetByPath(ctx.Context(), targetVersion, ctx.Path())
+	location, status, redirect, err := r.service.VersionManager().GetRedirect(ctx.Context(), targetVersion, ctx.Path())
+	if err != nil {
+		return err
+	}
+	if redirect {
+		if query := ctx.Request().URI().QueryString(); len(query) > 0 && !strings.Contains(location, "?") {
+			location += "?" + string(query)
+		}
+		return ctx.Redirect(location, status)
+	}
+
+	site, err := r.service.GetSite(ctx.Context())
+	if err != nil {
+		return err
+	}
+	params := acquireParams()
+	defer releaseParams(params)
+	pageUID, err := r.service.VersionManager().GetByPath(ctx.Context(), targetVersion, ctx.Path(), params)
+	if err != nil && !errorx.IsOfType(err, core.ErrPageNotFound) {
+		return err
+	}
+	// A page at exactly the path of a feed takes priority over it, while a feed takes priority over pages matched
+	// by parameters or wildcards, so that a catch-all page does not hide the feeds.
+	if err != nil || len(*params) > 0 {
+		if feed, tag, ok := matchFeed(siteFeeds(site), ctx.Path()); ok {
+			return r.feed(ctx, site, targetVersion, feed, tag, cacheable, cacheKey)
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -28,20 +76,108 @@ func (r renderer) render(ctx *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
-	content, err := r.service.ContentManager().Get(ctx.Context(), page.Content)
+	blob, err := r.service.GetBlob(ctx.Context(), page.Content)
 	if err != nil {
 		return err
 	}
-	output, err := r.service.ThemeManager().Render(page.TemplateName, map[string]any{
-		"content": raymond.SafeString(content),
-	})
+	version, err := r.service.GetVersion(ctx.Context(), targetVersion.UID())
 	if err != nil {
 		return err
 	}
 
-	return ctx.SendString(string(output))
+	// Pages of the current version never change, while other versions are edited in place and the pages helper may
+	// list any of their pages.
+	revision := ""
+	if !targetVersion.IsCurrent() {
+		if revision, err = r.service.Collections().Revision(ctx.Context(), version.UID); err != nil {
+			return err
+		}
+	}
+	rendered := RenderedPage{
+		ETag: pageETag(version.UID, page, blob.Hash, version.Theme, site.Title, revision),
+	}
+	// The current version changes only when the site updates, while other versions change with every edit.
+	if targetVersion.IsCurrent() {
+		rendered.LastModified = site.UpdatedAt
+	}
+	if notModified(ctx, rendered.ETag, rendered.LastModified) {
+		setValidators(ctx, rendered.ETag, rendered.LastModified)
+		return ctx.SendStatus(http.StatusNotModified)
+	}
+
+	content, err := r.content(ctx, page, version.Theme)
+	if err != nil {
+		return err
+	}
+	data := newRenderContext(ctx, site, version, targetVersion, page, content, *params)
+	data.pages = newPageQuerier(ctx.Context(), r.service.Collections(), version.UID)
+	rendered.Body, err = r.service.ThemeManager().Render(ctx.Context(), version.Theme, page.TemplateName, data)
+	if err != nil {
+		return err
+	}
+
+	if cacheable {
+		r.service.RenderCache().Set(targetVersion.UID(), cacheKey, rendered)
+	}
+	return r.send(ctx, rendered)
+}
+
+// content returns the HTML content of a page, converting it from the content type of the page.
+func (r renderer) content(ctx *fiber.Ctx, page core.Page, theme ulid.ULID) ([]byte, error) {
+	if page.ContentType != core.ContentTypeBlocks {
+		return r.service.ContentManager().GetHTML(ctx.Context(), page.Content, page.ContentType)
+	}
+	content, err := r.service.ContentManager().Get(ctx.Context(), page.Content)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseBlockDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	return r.service.ThemeManager().RenderBlocks(ctx.Context(), theme, doc)
+}
+
+func (r renderer) send(ctx *fiber.Ctx, rendered RenderedPage) error {
+	setValidators(ctx, rendered.ETag, rendered.LastModified)
+	if notModified(ctx, rendered.ETag, rendered.LastModified) {
+		return ctx.SendStatus(http.StatusNotModified)
+	}
+	if rendered.ContentType != "" {
+		ctx.Set(fiber.HeaderContentType, rendered.ContentType)
+	} else {
+		ctx.Type("html")
+	}
+	return ctx.Send(rendered.Body)
+}
+
+// renderCacheKey identifies the parts of a request that the rendered output depends on: the path and query string.
+func renderCacheKey(ctx *fiber.Ctx) string {
+	key := ctx.Path()
+	if query := ctx.Request().URI().QueryString(); len(query) > 0 {
+		key += "?" + string(query)
+	}
+	return strings.Clone(key)
+}
+
+// asset serves a theme asset. Asset names include a hash of their content, so responses never change and may be
+// cached indefinitely.
+func (r renderer) asset(ctx *fiber.Ctx) error {
+	name := ctx.Params("name")
+	body, contentType, err := r.service.ThemeManager().Asset(name)
+	if err != nil {
+		return err
+	}
+	ctx.Set(fiber.HeaderCacheControl, r.rc.CacheControlAssets)
+	etag := `"` + strings.TrimSuffix(name, path.Ext(name)) + `"`
+	setValidators(ctx, etag, time.Time{})
+	if notModified(ctx, etag, time.Time{}) {
+		return ctx.SendStatus(http.StatusNotModified)
+	}
+	ctx.Set(fiber.HeaderContentType, contentType)
+	return ctx.Send(body)
 }
 
-func NewRenderer(service Service) *renderer {
-	return &renderer{service}
+func NewRenderer(rc *config.RuntimeConfig, service Service) *renderer {
+	return &renderer{rc, service}
 }
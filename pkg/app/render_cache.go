@@ -0,0 +1,131 @@
+package app
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+)
+
+// RenderCache holds rendered page output by version UID and request key. The total size of the cached output is kept
+// within a memory budget by evicting the least recently used entries.
+type RenderCache interface {
+	Get(version ulid.ULID, key string) (RenderedPage, bool)
+	Set(version ulid.ULID, key string, page RenderedPage)
+	// PurgeVersion removes every entry of a version, such as after an edit to the next version.
+	PurgeVersion(version ulid.ULID)
+	// Purge removes every entry, such as after a publish swaps the current version.
+	Purge()
+	Stats() core.RenderCacheStats
+}
+
+// RenderedPage is the output of rendering a page along with its cache validators. An empty content type is HTML.
+type RenderedPage struct {
+	Body         []byte
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+type renderCacheEntry struct {
+	version ulid.ULID
+	key     string
+	page    RenderedPage
+}
+
+func (e *renderCacheEntry) size() int64 {
+	return int64(len(e.key) + len(e.page.Body) + len(e.page.ETag) + len(e.page.ContentType))
+}
+
+type lruRenderCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	order    *list.List
+	entries  map[ulid.ULID]map[string]*list.Element
+	stats    core.RenderCacheStats
+}
+
+func (c *lruRenderCache) Get(version ulid.ULID, key string) (RenderedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[version][key]
+	if !ok {
+		c.stats.Misses++
+		return RenderedPage{}, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*renderCacheEntry).page, true
+}
+
+func (c *lruRenderCache) Set(version ulid.ULID, key string, page RenderedPage) {
+	entry := &renderCacheEntry{version, key, page}
+	if entry.size() > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[version][key]; ok {
+		c.remove(el)
+	}
+	keys, ok := c.entries[version]
+	if !ok {
+		keys = map[string]*list.Element{}
+		c.entries[version] = keys
+	}
+	keys[key] = c.order.PushFront(entry)
+	c.bytes += entry.size()
+	for c.bytes > c.maxBytes {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *lruRenderCache) PurgeVersion(version ulid.ULID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, el := range c.entries[version] {
+		c.remove(el)
+	}
+}
+
+func (c *lruRenderCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = map[ulid.ULID]map[string]*list.Element{}
+	c.bytes = 0
+}
+
+func (c *lruRenderCache) Stats() core.RenderCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	stats.Bytes = c.bytes
+	stats.MaxBytes = c.maxBytes
+	return stats
+}
+
+func (c *lruRenderCache) remove(el *list.Element) {
+	entry := c.order.Remove(el).(*renderCacheEntry)
+	c.bytes -= entry.size()
+	keys := c.entries[entry.version]
+	delete(keys, entry.key)
+	if len(keys) == 0 {
+		delete(c.entries, entry.version)
+	}
+}
+
+// NewRenderCache creates a render cache with the memory budget from runtime config. A budget of zero disables caching.
+func NewRenderCache(rc *config.RuntimeConfig) RenderCache {
+	return &lruRenderCache{
+		maxBytes: rc.RenderCacheMaxBytes,
+		order:    list.New(),
+		entries:  map[ulid.ULID]map[string]*list.Element{},
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderCache(t *testing.T) {
+	c := NewRenderCache(&config.RuntimeConfig{RenderCacheMaxBytes: 21})
+	current, next := ulid.Make(), ulid.Make()
+
+	c.Set(current, "/a", RenderedPage{Body: []byte("12345")})
+	c.Set(current, "/b", RenderedPage{Body: []byte("12345")})
+	c.Set(next, "/a", RenderedPage{Body: []byte("12345")})
+	body, ok := c.Get(current, "/a")
+	assert.True(t, ok)
+	assert.Equal(t, "12345", string(body.Body))
+	_, ok = c.Get(current, "/c")
+	assert.False(t, ok)
+
+	c.Set(next, "/b", RenderedPage{Body: []byte("12345")})
+	_, ok = c.Get(current, "/b")
+	assert.False(t, ok, "least recently used entry is evicted")
+
+	c.PurgeVersion(next)
+	_, ok = c.Get(next, "/a")
+	assert.False(t, ok)
+	_, ok = c.Get(current, "/a")
+	assert.True(t, ok)
+
+	c.Set(current, "/large", RenderedPage{Body: make([]byte, 100)})
+	_, ok = c.Get(current, "/large")
+	assert.False(t, ok, "entries over the budget are not cached")
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(4), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, int64(7), stats.Bytes)
+
+	c.Purge()
+	assert.Equal(t, 0, c.Stats().Entries)
+	assert.Equal(t, int64(0), c.Stats().Bytes)
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/aymerick/raymond"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RenderContext is the data that every theme template is executed with. The schema is stable across releases: fields
+// may be added, but existing fields are not renamed, removed or changed in type. Template names are given by the
+// handlebars tags, for example {{ page.title }} or {{ request.query.q }}.
+type RenderContext struct {
+	// Content is the rendered page content. Use {{{ content }}} to output it without escaping.
+	Content raymond.SafeString `handlebars:"content"`
+	// Params are the values captured by a parameter or wildcard page path, such as slug for /blog/:slug.
+	Params  map[string]string `handlebars:"params"`
+	Page    PageContext       `handlebars:"page"`
+	Site    SiteContext       `handlebars:"site"`
+	Version VersionContext    `handlebars:"version"`
+	Request RequestContext    `handlebars:"request"`
+	// Now is the time the page was rendered, in UTC. Rendered pages of the current version are cached and revalidated
+	// by an ETag that does not include it, so it can be much older than the request; use it for values such as a
+	// copyright year rather than to show the current time.
+	Now time.Time `handlebars:"now"`
+	// Error is only set for the error templates of a theme, such as "404" or "error".
+	Error *ErrorContext `handlebars:"error"`
+	// Search is only set for the search template of a theme.
+	Search *SearchContext `handlebars:"search"`
+
+	// pages runs the queries of the pages helper against the version being rendered.
+	pages pageQuerier
+}
+
+// PageContext is the metadata of the page being rendered.
+type PageContext struct {
+	UID          string   `handlebars:"uid"`
+	Title        string   `handlebars:"title"`
+	Path         string   `handlebars:"path"`
+	TemplateName string   `handlebars:"templateName"`
+	Tags         []string `handlebars:"tags"`
+	Excerpt      string   `handlebars:"excerpt"`
+	ContentType  string   `handlebars:"contentType"`
+	NoIndex      bool     `handlebars:"noIndex"`
+	// CreatedAt is when the page was first written, and is the date pages are sorted by.
+	CreatedAt time.Time `handlebars:"createdAt"`
+	UpdatedAt time.Time `handlebars:"updatedAt"`
+}
+
+// SiteContext is the metadata of the site.
+type SiteContext struct {
+	UID   string `handlebars:"uid"`
+	Title string `handlebars:"title"`
+}
+
+// VersionContext describes the version the page is rendered from. Exactly one of current, next and draft is set,
+// unless an older version is rendered through the X-Pagebin-Version header.
+type VersionContext struct {
+	UID         string    `handlebars:"uid"`
+	Current     bool      `handlebars:"current"`
+	Next        bool      `handlebars:"next"`
+	Draft       string    `handlebars:"draft"`
+	PublishedAt time.Time `handlebars:"publishedAt"`
+}
+
+// ErrorContext describes the error an error page is rendered for.
+type ErrorContext struct {
+	Status     int    `handlebars:"status"`
+	StatusText string `handlebars:"statusText"`
+	Message    string `handlebars:"message"`
+}
+
+// SearchContext is a page of the results of a search, ranked by relevance. Prev and Next are the numbers of the
+// neighbouring pages of results, or 0 when there is none.
+type SearchContext struct {
+	Query     string                `handlebars:"query"`
+	Total     int                   `handlebars:"total"`
+	Page      int                   `handlebars:"page"`
+	PageCount int                   `handlebars:"pageCount"`
+	Prev      int                   `handlebars:"prev"`
+	Next      int                   `handlebars:"next"`
+	Results   []SearchResultContext `handlebars:"results"`
+}
+
+// SearchResultContext is a page that matched a search.
+type SearchResultContext struct {
+	Title   string `handlebars:"title"`
+	Path    string `handlebars:"path"`
+	Excerpt string `handlebars:"excerpt"`
+	// Snippet is the text around the first match, with the matched words in mark elements. Use {{{ snippet }}} to
+	// output it without escaping.
+	Snippet raymond.SafeString `handlebars:"snippet"`
+}
+
+// RequestContext describes the request being rendered.
+type RequestContext struct {
+	Path  string            `handlebars:"path"`
+	Query map[string]string `handlebars:"query"`
+}
+
+func newRenderContext(ctx *fiber.Ctx, site core.Site, version core.Version, target *core.TargetVersion, page core.Page, content []byte, params Params) RenderContext {
+	c := newBaseRenderContext(ctx, site, version, target)
+	c.Content = raymond.SafeString(content)
+	c.Params = params.Map()
+	c.Page = newPageContext(page)
+	return c
+}
+
+func newPageContext(page core.Page) PageContext {
+	tags := page.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return PageContext{
+		UID:          page.UID.String(),
+		Title:        page.Title,
+		Path:         page.Path,
+		TemplateName: page.TemplateName,
+		Tags:         tags,
+		Excerpt:      page.Excerpt,
+		ContentType:  page.ContentType,
+		NoIndex:      page.NoIndex,
+		CreatedAt:    page.CreatedAt,
+		UpdatedAt:    page.UpdatedAt,
+	}
+}
+
+func newErrorRenderContext(ctx *fiber.Ctx, site core.Site, version core.Version, target *core.TargetVersion, status int, message string) RenderContext {
+	c := newBaseRenderContext(ctx, site, version, target)
+	c.Params = map[string]string{}
+	c.Page = PageContext{Tags: []string{}}
+	c.Error = &ErrorContext{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    message,
+	}
+	return c
+}
+
+func newBaseRenderContext(ctx *fiber.Ctx, site core.Site, version core.Version, target *core.TargetVersion) RenderContext {
+	return RenderContext{
+		Site: SiteContext{
+			UID:   site.UID.String(),
+			Title: site.Title,
+		},
+		Version: VersionContext{
+			UID:         version.UID.String(),
+			Current:     target.IsCurrent(),
+			Next:        target.IsNext(),
+			Draft:       target.Draft(),
+			PublishedAt: version.PublishedAt,
+		},
+		Request: RequestContext{
+			Path:  ctx.Path(),
+			Query: ctx.Queries(),
+		},
+		Now: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renderContextFor renders source with the render context of a request to path.
+func renderContextFor(t *testing.T, path string, source string, newContext func(ctx *fiber.Ctx) RenderContext) string {
+	t.Helper()
+	app := fiber.New()
+	app.Get("*", func(ctx *fiber.Ctx) error {
+		return ctx.SendString(renderHelper(t, source, newContext(ctx)))
+	})
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestRenderContext(t *testing.T) {
+	site := core.Site{UID: ulid.Make(), Title: "Site"}
+	version := core.Version{UID: ulid.Make(), PublishedAt: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)}
+	page := core.Page{
+		UID:          ulid.Make(),
+		Title:        "Hello",
+		Path:         "/blog/:slug",
+		TemplateName: "post",
+		Tags:         []string{"a", "b"},
+		Excerpt:      "Short",
+		ContentType:  core.ContentTypeMarkdown,
+		NoIndex:      true,
+		CreatedAt:    time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:    time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC),
+	}
+	params := Params{{Key: "slug", Value: "hello"}}
+
+	testCases := []struct {
+		desc     string
+		source   string
+		expected string
+	}{
+		{"site", `{{site.uid}} {{site.title}}`, site.UID.String() + " Site"},
+		{"version", `{{version.uid}} {{version.current}} {{version.next}} [{{version.draft}}] {{formatDate version.publishedAt format="date"}}`,
+			version.UID.String() + " true false [] 2024-03-05"},
+		{"page", `{{page.uid}} {{page.title}} {{page.path}} {{page.templateName}} {{#each page.tags}}{{this}},{{/each}} {{page.excerpt}} {{page.contentType}} {{page.noIndex}}`,
+			page.UID.String() + " Hello /blog/:slug post a,b, Short markdown true"},
+		{"page dates", `{{formatDate page.createdAt format="date"}} {{formatDate page.updatedAt format="date"}}`, "2024-03-01 2024-03-02"},
+		{"request", `{{request.path}} {{request.query.q}}`, "/blog/hello x"},
+		{"params", `{{params.slug}}`, "hello"},
+		{"content", `{{{content}}}`, "<p>Hi</p>"},
+		{"no error or search", `{{#if error}}error{{/if}}{{#if search}}search{{/if}}`, ""},
+	}
+	for _, tc := range testCases {
+		actual := renderContextFor(t, "/blog/hello?q=x", tc.source, func(ctx *fiber.Ctx) RenderContext {
+			return newRenderContext(ctx, site, version, core.NewCurrentTargetVersion(version.UID), page, []byte("<p>Hi</p>"), params)
+		})
+		assert.Equal(t, tc.expected, actual, tc.desc)
+	}
+
+	draft := renderContextFor(t, "/", `{{version.current}} {{version.next}} {{version.draft}}`, func(ctx *fiber.Ctx) RenderContext {
+		return newRenderContext(ctx, site, version, core.NewDraftTargetVersion(version.UID, "feature"), page, nil, nil)
+	})
+	assert.Equal(t, "false false feature", draft)
+
+	before := time.Now().UTC().Format(time.DateOnly)
+	now := renderContextFor(t, "/", `{{formatDate now format="date"}}`, func(ctx *fiber.Ctx) RenderContext {
+		return newRenderContext(ctx, site, version, core.NewCurrentTargetVersion(version.UID), page, nil, nil)
+	})
+	assert.Contains(t, []string{before, time.Now().UTC().Format(time.DateOnly)}, now, "now is the render time")
+}
+
+func TestErrorRenderContext(t *testing.T) {
+	site := core.Site{UID: ulid.Make(), Title: "Site"}
+	version := core.Version{UID: ulid.Make()}
+	actual := renderContextFor(t, "/missing", `{{error.status}} {{error.statusText}} {{error.message}} {{request.path}} [{{page.title}}]`, func(ctx *fiber.Ctx) RenderContext {
+		return newErrorRenderContext(ctx, site, version, core.NewNextTargetVersion(version.UID), http.StatusNotFound, "no page")
+	})
+	assert.Equal(t, "404 Not Found no page /missing []", actual)
+}
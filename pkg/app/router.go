@@ -0,0 +1,287 @@
+package app
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	routeParamPrefix    = ':'
+	routeWildcardPrefix = '*'
+	// routeWildcardKey is the param key of a wildcard route that does not name its wildcard, such as /docs/*.
+	routeWildcardKey = "*"
+)
+
+// Param is a value captured from the request path by a named parameter or wildcard route.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params are the params captured by a route in the order they appear in the route.
+type Params []Param
+
+// Get returns the value of the param with the given key.
+func (ps Params) Get(key string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Map returns the params keyed by name for use in template data. It is nil when there are no params, which templates
+// treat the same as an empty map.
+func (ps Params) Map() map[string]string {
+	if len(ps) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(ps))
+	for _, p := range ps {
+		m[p.Key] = p.Value
+	}
+	return m
+}
+
+// pooledParamsCap is the capacity of pooled params, which covers routes with up to this many params without growing.
+const pooledParamsCap = 8
+
+var paramsPool = sync.Pool{
+	New: func() any {
+		params := make(Params, 0, pooledParamsCap)
+		return &params
+	},
+}
+
+// acquireParams returns empty params from a pool, to be returned with releaseParams once the values are no longer used.
+func acquireParams() *Params {
+	return paramsPool.Get().(*Params)
+}
+
+func releaseParams(params *Params) {
+	clear(*params)
+	*params = (*params)[:0]
+	paramsPool.Put(params)
+}
+
+// router is a radix tree of page paths. A path segment may be a named parameter (/blog/:slug), which matches any one
+// segment, and the last segment may be a wildcard (/docs/* or /docs/*rest), which matches the remainder of the path.
+// Static routes take priority over parameters, which take priority over wildcards.
+//
+// Parameter nodes are shared between routes regardless of the parameter name; values are captured by position and
+// named by the route that matches, so /blog/:slug and /blog/:id/edit may coexist.
+type router struct {
+	root *routeNode
+}
+
+type routeNode struct {
+	prefix   string
+	indices  string
+	children []*routeNode
+	param    *routeNode
+	wildcard *routeNode
+	leaf     bool
+	uid      ulid.ULID
+	keys     []string
+}
+
+func newRouter(pages map[string]ulid.ULID) *router {
+	r := &router{root: &routeNode{}}
+	for path, uid := range pages {
+		r.insert(path, uid)
+	}
+	return r
+}
+
+// insert adds or replaces the route for a path. Paths are expected to be valid according to validatePath.
+func (r *router) insert(path string, uid ulid.ULID) {
+	n := r.root
+	keys := []string{}
+	for path != "" {
+		switch path[0] {
+		case routeParamPrefix:
+			end := segmentEnd(path)
+			keys = append(keys, path[1:end])
+			if n.param == nil {
+				n.param = &routeNode{}
+			}
+			n, path = n.param, path[end:]
+		case routeWildcardPrefix:
+			key := path[1:]
+			if key == "" {
+				key = routeWildcardKey
+			}
+			keys = append(keys, key)
+			if n.wildcard == nil {
+				n.wildcard = &routeNode{}
+			}
+			n, path = n.wildcard, ""
+		default:
+			end := staticEnd(path)
+			n = n.insertStatic(path[:end])
+			path = path[end:]
+		}
+	}
+	n.leaf = true
+	n.uid = uid
+	n.keys = keys
+}
+
+// insertStatic walks or creates the static nodes for text below n, splitting nodes that share part of a prefix.
+func (n *routeNode) insertStatic(text string) *routeNode {
+	for text != "" {
+		i := strings.IndexByte(n.indices, text[0])
+		if i < 0 {
+			child := &routeNode{prefix: text}
+			n.indices += string(text[0])
+			n.children = append(n.children, child)
+			return child
+		}
+		child := n.children[i]
+		common := commonPrefix(child.prefix, text)
+		if common < len(child.prefix) {
+			split := &routeNode{
+				prefix:   child.prefix[common:],
+				indices:  child.indices,
+				children: child.children,
+				param:    child.param,
+				wildcard: child.wildcard,
+				leaf:     child.leaf,
+				uid:      child.uid,
+				keys:     child.keys,
+			}
+			*child = routeNode{
+				prefix:   child.prefix[:common],
+				indices:  string(split.prefix[0]),
+				children: []*routeNode{split},
+			}
+		}
+		n, text = child, text[common:]
+	}
+	return n
+}
+
+// remove deletes the route for a path. Nodes are left in place and only stop matching.
+func (r *router) remove(path string) {
+	if n := r.find(path); n != nil {
+		n.leaf = false
+		n.keys = nil
+	}
+}
+
+// find returns the node of an exact route path, without matching parameters against values.
+func (r *router) find(path string) *routeNode {
+	n := r.root
+	for path != "" && n != nil {
+		switch path[0] {
+		case routeParamPrefix:
+			n, path = n.param, path[segmentEnd(path):]
+		case routeWildcardPrefix:
+			n, path = n.wildcard, ""
+		default:
+			i := strings.IndexByte(n.indices, path[0])
+			if i < 0 || !strings.HasPrefix(path, n.children[i].prefix) {
+				return nil
+			}
+			n, path = n.children[i], path[len(n.children[i].prefix):]
+		}
+	}
+	return n
+}
+
+// lookup matches a request path and appends the captured params. It does not allocate when params has enough
+// capacity for the matched route.
+func (r *router) lookup(path string, params *Params) (ulid.ULID, bool) {
+	start := len(*params)
+	n, ok := r.root.match(path, params)
+	if !ok {
+		*params = (*params)[:start]
+		return ulid.ULID{}, false
+	}
+	for i, key := range n.keys {
+		(*params)[start+i].Key = key
+	}
+	return n.uid, true
+}
+
+func (n *routeNode) match(path string, params *Params) (*routeNode, bool) {
+	if path == "" && n.leaf {
+		return n, true
+	}
+	if path != "" {
+		if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+			child := n.children[i]
+			if strings.HasPrefix(path, child.prefix) {
+				if found, ok := child.match(path[len(child.prefix):], params); ok {
+					return found, true
+				}
+			}
+		}
+		if n.param != nil {
+			end := segmentEnd(path)
+			if end > 0 {
+				*params = append(*params, Param{Value: path[:end]})
+				if found, ok := n.param.match(path[end:], params); ok {
+					return found, true
+				}
+				*params = (*params)[:len(*params)-1]
+			}
+		}
+	}
+	if n.wildcard != nil && n.wildcard.leaf {
+		*params = append(*params, Param{Value: path})
+		return n.wildcard, true
+	}
+	return nil, false
+}
+
+// validatePath checks that a page path starts with a slash, that parameters are named and take a whole segment, and
+// that a wildcard is only used as the last segment.
+func validatePath(path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return core.ErrInvalidPath.New("path \"%s\" must start with /", path)
+	}
+	segments := strings.Split(path[1:], "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, string(routeParamPrefix)):
+			if len(segment) == 1 {
+				return core.ErrInvalidPath.New("path \"%s\" has an unnamed parameter", path)
+			}
+		case strings.HasPrefix(segment, string(routeWildcardPrefix)):
+			if i != len(segments)-1 {
+				return core.ErrInvalidPath.New("path \"%s\" may only have a wildcard as the last segment", path)
+			}
+		}
+	}
+	return nil
+}
+
+// staticEnd returns the index where a parameter or wildcard segment starts, or the length of the path.
+func staticEnd(path string) int {
+	for i := 0; i < len(path)-1; i++ {
+		if path[i] == '/' && (path[i+1] == routeParamPrefix || path[i+1] == routeWildcardPrefix) {
+			return i + 1
+		}
+	}
+	return len(path)
+}
+
+func segmentEnd(path string) int {
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		return i
+	}
+	return len(path)
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterLookup(t *testing.T) {
+	routes := []string{
+		"/",
+		"/about",
+		"/about-us",
+		"/blog",
+		"/blog/archive",
+		"/blog/:slug",
+		"/blog/:id/edit",
+		"/docs/*",
+		"/docs/api/*rest",
+		"/docs/intro",
+		"/files/:name/*path",
+	}
+	pages := make(map[string]ulid.ULID, len(routes))
+	for _, r := range routes {
+		pages[r] = ulid.Make()
+	}
+	r := newRouter(pages)
+
+	testCases := []struct {
+		path     string
+		route    string
+		expected Params
+	}{
+		{path: "/", route: "/"},
+		{path: "/about", route: "/about"},
+		{path: "/about-us", route: "/about-us"},
+		{path: "/blog", route: "/blog"},
+		{path: "/blog/archive", route: "/blog/archive"},
+		{path: "/blog/hello-world", route: "/blog/:slug", expected: Params{{"slug", "hello-world"}}},
+		{path: "/blog/42/edit", route: "/blog/:id/edit", expected: Params{{"id", "42"}}},
+		{path: "/docs/intro", route: "/docs/intro"},
+		{path: "/docs/guide/install", route: "/docs/*", expected: Params{{"*", "guide/install"}}},
+		{path: "/docs/", route: "/docs/*", expected: Params{{"*", ""}}},
+		{path: "/docs/api/pages/put", route: "/docs/api/*rest", expected: Params{{"rest", "pages/put"}}},
+		{path: "/files/report/2024/q1.pdf", route: "/files/:name/*path", expected: Params{{"name", "report"}, {"path", "2024/q1.pdf"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			var params Params
+			uid, ok := r.lookup(tc.path, &params)
+			assert.True(t, ok)
+			assert.Equal(t, pages[tc.route], uid)
+			if tc.expected == nil {
+				assert.Empty(t, params)
+			} else {
+				assert.Equal(t, tc.expected, params)
+			}
+		})
+	}
+
+	for _, path := range []string{"/abou", "/about/", "/blog/", "/blog/a/b", "/docs", "/missing"} {
+		t.Run("no match "+path, func(t *testing.T) {
+			var params Params
+			_, ok := r.lookup(path, &params)
+			assert.False(t, ok)
+			assert.Empty(t, params)
+		})
+	}
+}
+
+func TestRouterInsertRemove(t *testing.T) {
+	r := newRouter(map[string]ulid.ULID{})
+	first, second := ulid.Make(), ulid.Make()
+	r.insert("/blog/:slug", first)
+	r.insert("/blog/:slug", second)
+
+	var params Params
+	uid, ok := r.lookup("/blog/post", &params)
+	assert.True(t, ok)
+	assert.Equal(t, second, uid)
+
+	r.insert("/blo", first)
+	params = params[:0]
+	uid, ok = r.lookup("/blog/post", &params)
+	assert.True(t, ok, "splitting a node keeps its children")
+	assert.Equal(t, second, uid)
+
+	r.remove("/blog/:slug")
+	params = params[:0]
+	_, ok = r.lookup("/blog/post", &params)
+	assert.False(t, ok)
+	uid, ok = r.lookup("/blo", &params)
+	assert.True(t, ok)
+	assert.Equal(t, first, uid)
+}
+
+func TestValidatePath(t *testing.T) {
+	for _, path := range []string{"/", "/about", "/blog/:slug", "/docs/*", "/docs/*rest", "/a:b"} {
+		assert.NoError(t, validatePath(path), path)
+	}
+	for _, path := range []string{"", "about", "/blog/:", "/docs/*/more"} {
+		assert.Error(t, validatePath(path), path)
+	}
+}
+
+func benchmarkPages(n int) map[string]ulid.ULID {
+	pages := make(map[string]ulid.ULID, n)
+	for i := 0; i < n; i++ {
+		pages[fmt.Sprintf("/section-%d/page-%d", i%20, i)] = ulid.Make()
+	}
+	return pages
+}
+
+func BenchmarkRouterStatic(b *testing.B) {
+	r := newRouter(benchmarkPages(1000))
+	params := make(Params, 0, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		r.lookup("/section-7/page-507", &params)
+	}
+}
+
+func BenchmarkRouterParam(b *testing.B) {
+	pages := benchmarkPages(1000)
+	pages["/blog/:slug"] = ulid.Make()
+	r := newRouter(pages)
+	params := make(Params, 0, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		r.lookup("/blog/hello-world", &params)
+	}
+}
+
+func BenchmarkMapStatic(b *testing.B) {
+	pages := benchmarkPages(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = pages["/section-7/page-507"]
+	}
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/aarongodin/pagebin/pkg/store"
+	"github.com/rs/zerolog/log"
+)
+
+// idleInterval is how long the scheduler sleeps when no schedules are pending. Creating or deleting a schedule wakes
+// it early through Notify.
+const idleInterval = time.Hour
+
+// Scheduler runs pending schedules at their run time.
+type Scheduler interface {
+	Start(ctx context.Context) error
+	Stop()
+	Notify()
+}
+
+type scheduler struct {
+	policy    string
+	schedules store.ScheduleStore
+	publish   func(ctx context.Context, schedule core.Schedule) (core.Site, error)
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// Start applies the missed policy to schedules that should have run while the server was down and starts running
+// pending schedules in the background.
+func (s *scheduler) Start(ctx context.Context) error {
+	if s.policy == config.SchedulerMissedPolicySkip {
+		pending, err := s.schedules.GetPendingSchedules(ctx)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, schedule := range pending {
+			if schedule.RunAt.After(now) {
+				break
+			}
+			schedule.Status = core.ScheduleStatusSkipped
+			schedule.RanAt = now.UTC()
+			if _, err := s.schedules.UpdateSchedule(ctx, schedule); err != nil {
+				return err
+			}
+			log.Info().Str("scheduleUID", schedule.UID.String()).Msg("skipped missed schedule")
+		}
+	}
+	go s.run(ctx)
+	return nil
+}
+
+// Stop waits for a running schedule to finish and stops the scheduler.
+func (s *scheduler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Notify wakes the scheduler so that it picks up changes to the pending schedules.
+func (s *scheduler) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *scheduler) run(ctx context.Context) {
+	defer close(s.done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+		wait, err := s.tick(ctx)
+		if err != nil {
+			log.Err(err).Msg("failed running schedules")
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// tick runs every schedule that is due and returns how long to wait until the next one.
+func (s *scheduler) tick(ctx context.Context) (time.Duration, error) {
+	pending, err := s.schedules.GetPendingSchedules(ctx)
+	if err != nil {
+		return idleInterval, err
+	}
+	for _, schedule := range pending {
+		if wait := time.Until(schedule.RunAt); wait > 0 {
+			return min(wait, idleInterval), nil
+		}
+		s.runSchedule(ctx, schedule)
+	}
+	return idleInterval, nil
+}
+
+func (s *scheduler) runSchedule(ctx context.Context, schedule core.Schedule) {
+	logger := log.With().Str("scheduleUID", schedule.UID.String()).Str("versionUID", schedule.Version.String()).Logger()
+	schedule.Status = core.ScheduleStatusDone
+	if _, err := s.publish(ctx, schedule); err != nil {
+		logger.Err(err).Msg("scheduled publish failed")
+		schedule.Status = core.ScheduleStatusFailed
+		schedule.Error = err.Error()
+	} else {
+		logger.Info().Msg("scheduled publish complete")
+	}
+	schedule.RanAt = time.Now().UTC()
+	if _, err := s.schedules.UpdateSchedule(ctx, schedule); err != nil {
+		logger.Err(err).Msg("failed saving schedule status")
+	}
+}
+
+func NewScheduler(rc *config.RuntimeConfig, schedules store.ScheduleStore, publish func(ctx context.Context, schedule core.Schedule) (core.Site, error)) (Scheduler, error) {
+	switch rc.SchedulerMissedPolicy {
+	case config.SchedulerMissedPolicyRun, config.SchedulerMissedPolicySkip:
+	default:
+		return nil, core.ErrUnknownMissedPolicy.New("unknown scheduler missed policy \"%s\"", rc.SchedulerMissedPolicy)
+	}
+	return &scheduler{
+		policy:    rc.SchedulerMissedPolicy,
+		schedules: schedules,
+		publish:   publish,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}, nil
+}
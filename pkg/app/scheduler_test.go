@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPublisher records the schedules it publishes, failing those with a message of "fail".
+type testPublisher struct {
+	mu        sync.Mutex
+	published []ulid.ULID
+}
+
+func (p *testPublisher) publish(_ context.Context, schedule core.Schedule) (core.Site, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, schedule.UID)
+	if schedule.Message == "fail" {
+		return core.Site{}, errors.New("publish failed")
+	}
+	return core.Site{}, nil
+}
+
+func (p *testPublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.published)
+}
+
+func TestNewSchedulerRejectsUnknownPolicy(t *testing.T) {
+	s := newTestService(t)
+	for _, policy := range []string{"", "Skip", "runn"} {
+		rc := *s.rc
+		rc.SchedulerMissedPolicy = policy
+		_, err := NewService(&rc, s.store)
+		assert.True(t, errorx.IsOfType(err, core.ErrUnknownMissedPolicy), policy)
+	}
+}
+
+func TestSchedulerRunsDueSchedules(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	schedules := s.store.Schedules()
+	now := time.Now()
+	ok, err := schedules.CreateSchedule(ctx, ulid.Make(), now.Add(-time.Minute), "admin", "ok")
+	require.NoError(t, err)
+	failed, err := schedules.CreateSchedule(ctx, ulid.Make(), now.Add(-time.Second), "admin", "fail")
+	require.NoError(t, err)
+	future, err := schedules.CreateSchedule(ctx, ulid.Make(), now.Add(10*time.Minute), "admin", "future")
+	require.NoError(t, err)
+
+	publisher := &testPublisher{}
+	sched, err := NewScheduler(s.rc, schedules, publisher.publish)
+	require.NoError(t, err)
+	wait, err := sched.(*scheduler).tick(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 10*time.Minute, wait, float64(time.Minute), "waits until the next schedule")
+	assert.Equal(t, []ulid.ULID{ok.UID, failed.UID}, publisher.published, "due schedules run in order")
+
+	ok, err = schedules.GetSchedule(ctx, ok.UID)
+	require.NoError(t, err)
+	assert.Equal(t, core.ScheduleStatusDone, ok.Status)
+	assert.False(t, ok.RanAt.IsZero())
+	failed, err = schedules.GetSchedule(ctx, failed.UID)
+	require.NoError(t, err)
+	assert.Equal(t, core.ScheduleStatusFailed, failed.Status)
+	assert.Equal(t, "publish failed", failed.Error)
+	future, err = schedules.GetSchedule(ctx, future.UID)
+	require.NoError(t, err)
+	assert.Equal(t, core.ScheduleStatusPending, future.Status)
+
+	require.NoError(t, schedules.DeleteSchedule(ctx, future.UID))
+	wait, err = sched.(*scheduler).tick(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, idleInterval, wait, "waits the idle interval without pending schedules")
+}
+
+func TestSchedulerSkipsMissedSchedules(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, func(rc *config.RuntimeConfig) {
+		rc.SchedulerMissedPolicy = config.SchedulerMissedPolicySkip
+	})
+	schedules := s.store.Schedules()
+	missed, err := schedules.CreateSchedule(ctx, ulid.Make(), time.Now().Add(-time.Hour), "admin", "missed")
+	require.NoError(t, err)
+	future, err := schedules.CreateSchedule(ctx, ulid.Make(), time.Now().Add(time.Hour), "admin", "future")
+	require.NoError(t, err)
+
+	publisher := &testPublisher{}
+	sched, err := NewScheduler(s.rc, schedules, publisher.publish)
+	require.NoError(t, err)
+	require.NoError(t, sched.Start(ctx))
+	sched.Stop()
+	assert.Zero(t, publisher.count())
+
+	missed, err = schedules.GetSchedule(ctx, missed.UID)
+	require.NoError(t, err)
+	assert.Equal(t, core.ScheduleStatusSkipped, missed.Status)
+	assert.False(t, missed.RanAt.IsZero())
+	future, err = schedules.GetSchedule(ctx, future.UID)
+	require.NoError(t, err)
+	assert.Equal(t, core.ScheduleStatusPending, future.Status)
+}
+
+func TestSchedulerNotify(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	publisher := &testPublisher{}
+	sched, err := NewScheduler(s.rc, s.store.Schedules(), publisher.publish)
+	require.NoError(t, err)
+	require.NoError(t, sched.Start(ctx))
+	defer sched.Stop()
+
+	_, err = s.store.Schedules().CreateSchedule(ctx, ulid.Make(), time.Now(), "admin", "now")
+	require.NoError(t, err)
+	sched.Notify()
+	assert.Eventually(t, func() bool {
+		return publisher.count() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
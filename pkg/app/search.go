@@ -0,0 +1,492 @@
+package app
+
+import (
+	"context"
+	"html"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/aymerick/raymond"
+	"github.com/gofiber/fiber/v2"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	// templateSearch is the theme template that renders the search page. Themes without it have no search page.
+	templateSearch = "search"
+
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	// maxSearchTerms is the most terms of a query that are searched for.
+	maxSearchTerms = 32
+	// snippetWords is the number of words in a snippet, of which snippetLeadWords come before the first match.
+	snippetWords     = 30
+	snippetLeadWords = 8
+
+	// bm25K1 and bm25B are the term frequency saturation and length normalization of BM25 ranking.
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// wordSpan is the byte range of a word in a text.
+type wordSpan struct {
+	start int
+	end   int
+}
+
+// wordSpans splits text into words, which are runs of letters and digits.
+func wordSpans(text string) []wordSpan {
+	var spans []wordSpan
+	start := -1
+	for i, r := range text {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			spans = append(spans, wordSpan{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, wordSpan{start, len(text)})
+	}
+	return spans
+}
+
+// searchTerm returns the term a word is indexed as, or false for stop words.
+func searchTerm(word string) (string, bool) {
+	word = strings.ToLower(word)
+	if _, ok := stopWords[word]; ok {
+		return "", false
+	}
+	return stem(word), true
+}
+
+// tokenize returns the terms of text in order.
+func tokenize(text string) []string {
+	var terms []string
+	for _, span := range wordSpans(text) {
+		if term, ok := searchTerm(text[span.start:span.end]); ok {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// searchable reports whether a page is indexed. Pages with params have no single URL to link to, and pages marked
+// noIndex are left out of search the same as the sitemap.
+func searchable(page core.Page) bool {
+	return !page.NoIndex && !strings.ContainsAny(page.Path, string(routeParamPrefix)+string(routeWildcardPrefix))
+}
+
+// newSearchDocument indexes the title, excerpt, tags and content of a page. Title terms are counted twice so that
+// pages about a term rank above pages that mention it.
+func newSearchDocument(page core.Page, content []byte) (core.SearchDocument, error) {
+	text, err := contentText(page.ContentType, content)
+	if err != nil {
+		return core.SearchDocument{}, err
+	}
+	doc := core.SearchDocument{Page: page.UID, Terms: map[string]int{}, Text: text}
+	title := tokenize(page.Title)
+	for _, terms := range [][]string{title, title, tokenize(page.Excerpt), tokenize(strings.Join(page.Tags, " ")), tokenize(text)} {
+		for _, term := range terms {
+			doc.Terms[term]++
+			doc.Length++
+		}
+	}
+	return doc, nil
+}
+
+// contentText returns the plain text of page content.
+func contentText(contentType string, content []byte) (string, error) {
+	switch contentType {
+	case "", core.ContentTypeHTML:
+		return htmlText(string(content)), nil
+	case core.ContentTypeBlocks:
+		doc, err := parseBlockDocument(content)
+		if err != nil {
+			return "", err
+		}
+		var parts []string
+		blocksText(doc.Blocks, &parts)
+		return strings.Join(parts, " "), nil
+	}
+	output, err := convertContent(contentType, content)
+	if err != nil {
+		return "", err
+	}
+	return htmlText(string(output)), nil
+}
+
+// blocksText gathers the text of blocks. Every string in the data of a block is text, other than URLs and code
+// languages.
+func blocksText(blocks []core.Block, parts *[]string) {
+	for _, block := range blocks {
+		keys := make([]string, 0, len(block.Data))
+		for key := range block.Data {
+			if key != "url" && key != "language" && key != "id" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			dataText(block.Data[key], parts)
+		}
+		for _, column := range block.Columns {
+			blocksText(column, parts)
+		}
+	}
+}
+
+func dataText(value any, parts *[]string) {
+	switch v := value.(type) {
+	case string:
+		if text := htmlText(v); text != "" {
+			*parts = append(*parts, text)
+		}
+	case []any:
+		for _, item := range v {
+			dataText(item, parts)
+		}
+	}
+}
+
+// htmlText strips the tags from HTML, along with the contents of script and style elements, and collapses whitespace.
+func htmlText(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		open := strings.IndexByte(s, '<')
+		if open < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:open])
+		b.WriteByte(' ')
+		s = s[open:]
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.ToLower(s[1:end])
+		s = s[end+1:]
+		for _, raw := range []string{"script", "style"} {
+			if tag == raw || strings.HasPrefix(tag, raw+" ") {
+				if closing := strings.Index(strings.ToLower(s), "</"+raw); closing >= 0 {
+					s = s[closing:]
+				} else {
+					s = ""
+				}
+			}
+		}
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(b.String())), " ")
+}
+
+// snippet returns about snippetWords words of text around the first word that matches one of the terms, as HTML with
+// each matching word in a mark element. Text without a match gives a snippet of its first words.
+func snippet(text string, terms map[string]bool) string {
+	spans := wordSpans(text)
+	if len(spans) == 0 {
+		return ""
+	}
+	matches := make([]bool, len(spans))
+	first := -1
+	for i, span := range spans {
+		if term, ok := searchTerm(text[span.start:span.end]); ok && terms[term] {
+			matches[i] = true
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	start := 0
+	if first > snippetLeadWords {
+		start = first - snippetLeadWords
+	}
+	start = max(0, min(start, len(spans)-snippetWords))
+	end := min(start+snippetWords, len(spans))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if i > start {
+			b.WriteString(html.EscapeString(text[spans[i-1].end:spans[i].start]))
+		}
+		word := html.EscapeString(text[spans[i].start:spans[i].end])
+		if matches[i] {
+			b.WriteString("<mark>" + word + "</mark>")
+		} else {
+			b.WriteString(word)
+		}
+	}
+	if end < len(spans) {
+		b.WriteString("…")
+	} else {
+		b.WriteString(html.EscapeString(strings.TrimRightFunc(text[spans[end-1].end:], unicode.IsSpace)))
+	}
+	return b.String()
+}
+
+// scoredPage is a page that matched a search, with its BM25 score.
+type scoredPage struct {
+	page  ulid.ULID
+	score float64
+}
+
+// rankBM25 scores the pages of the postings by BM25 and sorts them from the most to the least relevant.
+func rankBM25(postings core.SearchPostings) []scoredPage {
+	n := float64(postings.Stats.Documents)
+	avgLength := float64(postings.Stats.Length) / max(n, 1)
+	scores := map[ulid.ULID]float64{}
+	for _, pages := range postings.Terms {
+		df := float64(len(pages))
+		idf := math.Log((n-df+0.5)/(df+0.5) + 1)
+		for page, tf := range pages {
+			norm := 1 - bm25B + bm25B*float64(postings.Lengths[page])/max(avgLength, 1)
+			scores[page] += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+		}
+	}
+	ranked := make([]scoredPage, 0, len(scores))
+	for page, score := range scores {
+		ranked = append(ranked, scoredPage{page, score})
+	}
+	slices.SortFunc(ranked, func(a, b scoredPage) int {
+		if a.score != b.score {
+			if a.score > b.score {
+				return -1
+			}
+			return 1
+		}
+		return a.page.Compare(b.page)
+	})
+	return ranked
+}
+
+// queryTerms returns the distinct terms of a search query.
+func queryTerms(query string) []string {
+	var terms []string
+	for _, term := range tokenize(query) {
+		if !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms[:min(len(terms), maxSearchTerms)]
+}
+
+// Search ranks the pages of the target version that match a query. The search index of a version is built on its
+// first search and then kept up to date as pages are written.
+func (s *Svc) Search(ctx context.Context, target *core.TargetVersion, query string, limit int, page int) (results core.SearchResults, txErr error) {
+	switch {
+	case limit < 0 || limit > maxSearchLimit:
+		return results, core.ErrInvalidSearch.New("limit must be from 0 to %d", maxSearchLimit)
+	case limit == 0:
+		limit = defaultSearchLimit
+	}
+	results = core.SearchResults{Query: query, Page: max(page, 1), PageCount: 1, Results: []core.SearchResult{}}
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+	if err := s.buildSearchIndex(ctx, target.UID()); err != nil {
+		return results, err
+	}
+
+	ctx, err := s.store.StartTx(ctx, false)
+	if err != nil {
+		return results, err
+	}
+	defer func() {
+		txErr = s.store.EndTx(ctx, txErr)
+	}()
+	postings, err := s.store.Search().Postings(ctx, target.UID(), terms)
+	if err != nil {
+		return results, err
+	}
+	ranked := rankBM25(postings)
+	results.Total = len(ranked)
+	results.PageCount = max(1, (len(ranked)+limit-1)/limit)
+	start := min((results.Page-1)*limit, len(ranked))
+	ranked = ranked[start:min(start+limit, len(ranked))]
+
+	uids := make([]ulid.ULID, len(ranked))
+	for i, r := range ranked {
+		uids[i] = r.page
+	}
+	docs, err := s.store.Search().GetDocuments(ctx, target.UID(), uids)
+	if err != nil {
+		return results, err
+	}
+	matched := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		matched[term] = true
+	}
+	for i, r := range ranked {
+		p, err := s.GetPage(ctx, r.page)
+		if err != nil {
+			return results, err
+		}
+		results.Results = append(results.Results, core.SearchResult{
+			Page:    p.UID,
+			Title:   p.Title,
+			Path:    p.Path,
+			Excerpt: p.Excerpt,
+			Snippet: snippet(docs[i].Text, matched),
+			Score:   r.score,
+		})
+	}
+	return results, nil
+}
+
+// buildSearchIndex indexes every page of a version that is not indexed yet.
+func (s *Svc) buildSearchIndex(ctx context.Context, versionUID ulid.ULID) (txErr error) {
+	if exists, err := s.store.Search().Exists(ctx, versionUID); err != nil || exists {
+		return err
+	}
+	ctx, err := s.store.StartTx(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		txErr = s.store.EndTx(ctx, txErr)
+	}()
+	// another search may have built the index while this one waited for the transaction
+	if exists, err := s.store.Search().Exists(ctx, versionUID); err != nil || exists {
+		return err
+	}
+	version, err := s.GetVersion(ctx, versionUID)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Search().CreateVersion(ctx, versionUID); err != nil {
+		return err
+	}
+	for _, pageUID := range version.Pages {
+		page, err := s.GetPage(ctx, pageUID)
+		if err != nil {
+			return err
+		}
+		if !searchable(page) {
+			continue
+		}
+		content, err := s.store.Blobs().GetBytes(ctx, page.Content)
+		if err != nil {
+			return err
+		}
+		doc, err := newSearchDocument(page, content)
+		if err != nil {
+			return err
+		}
+		if err := s.store.Search().PutDocument(ctx, versionUID, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexPage updates the search index of a version, when it has one, after a page is written. previous is the page
+// that was written over, which differs from page when the version got its own copy of a shared page.
+func (s *Svc) indexPage(ctx context.Context, versionUID ulid.ULID, previous *ulid.ULID, page core.Page, content []byte) error {
+	if exists, err := s.store.Search().Exists(ctx, versionUID); err != nil || !exists {
+		return err
+	}
+	if previous != nil && *previous != page.UID {
+		if err := s.store.Search().DeleteDocument(ctx, versionUID, *previous); err != nil {
+			return err
+		}
+	}
+	if !searchable(page) {
+		return s.store.Search().DeleteDocument(ctx, versionUID, page.UID)
+	}
+	doc, err := newSearchDocument(page, content)
+	if err != nil {
+		return err
+	}
+	return s.store.Search().PutDocument(ctx, versionUID, doc)
+}
+
+// search renders the search page of the theme with the results for the q and page query parameters.
+func (r renderer) search(ctx *fiber.Ctx) error {
+	targetVersion, err := getTargetVersion(ctx, r.service, false)
+	if err != nil {
+		return err
+	}
+	cacheable := targetVersion.IsCurrent() && len(ctx.Get(HeaderPagebinVersion)) == 0
+	if cacheable {
+		ctx.Set(fiber.HeaderCacheControl, r.rc.CacheControlPages)
+	} else {
+		ctx.Set(fiber.HeaderCacheControl, r.rc.CacheControlPreviews)
+	}
+	cacheKey := renderCacheKey(ctx)
+	if cacheable {
+		if rendered, ok := r.service.RenderCache().Get(targetVersion.UID(), cacheKey); ok {
+			return r.send(ctx, rendered)
+		}
+	}
+
+	site, err := r.service.GetSite(ctx.Context())
+	if err != nil {
+		return err
+	}
+	version, err := r.service.GetVersion(ctx.Context(), targetVersion.UID())
+	if err != nil {
+		return err
+	}
+	results, err := r.service.Search(ctx.Context(), targetVersion, ctx.Query("q"), 0, ctx.QueryInt("page", 1))
+	if err != nil {
+		return err
+	}
+	data := newBaseRenderContext(ctx, site, version, targetVersion)
+	data.Params = map[string]string{}
+	data.Page = PageContext{Title: "Search", Path: pathSearch, Tags: []string{}}
+	data.Search = newSearchContext(results)
+	data.pages = newPageQuerier(ctx.Context(), r.service.Collections(), version.UID)
+
+	rendered := RenderedPage{}
+	if targetVersion.IsCurrent() {
+		rendered.LastModified = site.UpdatedAt
+	}
+	rendered.Body, err = r.service.ThemeManager().Render(ctx.Context(), version.Theme, templateSearch, data)
+	if errorx.IsOfType(err, core.ErrThemeTemplateNotFound) {
+		return core.ErrPageNotFound.New("theme has no %s template", templateSearch)
+	} else if err != nil {
+		return err
+	}
+	rendered.ETag = bodyETag(rendered.Body)
+	if cacheable {
+		r.service.RenderCache().Set(targetVersion.UID(), cacheKey, rendered)
+	}
+	return r.send(ctx, rendered)
+}
+
+func newSearchContext(results core.SearchResults) *SearchContext {
+	c := &SearchContext{
+		Query:     results.Query,
+		Total:     results.Total,
+		Page:      results.Page,
+		PageCount: results.PageCount,
+		Results:   make([]SearchResultContext, len(results.Results)),
+	}
+	if c.Page > 1 && c.Page <= c.PageCount {
+		c.Prev = c.Page - 1
+	}
+	if c.Page < c.PageCount {
+		c.Next = c.Page + 1
+	}
+	for i, result := range results.Results {
+		c.Results[i] = SearchResultContext{
+			Title:   result.Title,
+			Path:    result.Path,
+			Excerpt: result.Excerpt,
+			Snippet: raymond.SafeString(result.Snippet),
+		}
+	}
+	return c
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"connect", "connect", "2024"}, tokenize("The Connected connections, of 2024!"))
+	assert.Empty(t, tokenize("the and of"))
+	assert.Equal(t, []string{"naïve", "café"}, tokenize("Naïve CAFÉ"), "words that are not ASCII are not stemmed")
+}
+
+func TestContentText(t *testing.T) {
+	text, err := contentText(core.ContentTypeHTML, []byte(`<h1>Hello &amp; welcome</h1><script>var x = "<b>";</script><p>to the<br>site</p>`))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello & welcome to the site", text)
+
+	text, err = contentText(core.ContentTypeMarkdown, []byte("# Title\n\nSome *emphasis* here."))
+	require.NoError(t, err)
+	assert.Equal(t, "Title Some emphasis here.", text)
+
+	text, err = contentText(core.ContentTypeBlocks, []byte(`{"version":1,"blocks":[
+		{"type":"paragraph","data":{"text":"First <b>bold</b>"}},
+		{"type":"image","data":{"url":"/cat.png","alt":"A cat"}},
+		{"type":"columns","columns":[[{"type":"list","data":{"items":["one","two"]}}]]}
+	]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "First bold A cat one two", text)
+}
+
+func TestNewSearchDocument(t *testing.T) {
+	page := core.Page{UID: ulid.Make(), Title: "Routing", Tags: []string{"networks"}, ContentType: core.ContentTypeHTML}
+	doc, err := newSearchDocument(page, []byte("<p>Packets are routed.</p>"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"rout": 3, "network": 1, "packet": 1}, doc.Terms, "title terms are counted twice")
+	assert.Equal(t, 5, doc.Length)
+	assert.Equal(t, "Packets are routed.", doc.Text)
+}
+
+func TestSearchable(t *testing.T) {
+	assert.True(t, searchable(core.Page{Path: "/about"}))
+	assert.False(t, searchable(core.Page{Path: "/about", NoIndex: true}))
+	assert.False(t, searchable(core.Page{Path: "/blog/:slug"}))
+	assert.False(t, searchable(core.Page{Path: "/files/*path"}))
+}
+
+func TestRankBM25(t *testing.T) {
+	short, long, other := ulid.Make(), ulid.Make(), ulid.Make()
+	postings := core.SearchPostings{
+		Stats: core.SearchStats{Documents: 10, Length: 500},
+		Terms: map[string]map[ulid.ULID]int{
+			"cat": {short: 2, long: 2},
+			"dog": {other: 2},
+		},
+		Lengths: map[ulid.ULID]int{short: 40, long: 60, other: 50},
+	}
+	ranked := rankBM25(postings)
+	require.Len(t, ranked, 3)
+	assert.Equal(t, other, ranked[0].page, "a rarer term weighs more")
+	assert.Equal(t, short, ranked[1].page, "a shorter page with the same term frequency ranks higher")
+	assert.Equal(t, long, ranked[2].page)
+
+	postings.Terms["dog"] = map[ulid.ULID]int{other: 2, long: 2}
+	ranked = rankBM25(postings)
+	assert.Equal(t, long, ranked[0].page, "a page with more of the terms ranks higher")
+}
+
+func TestSnippet(t *testing.T) {
+	terms := map[string]bool{"rout": true}
+	assert.Equal(t, "<mark>Routing</mark> is &lt;b&gt;fast&lt;/b&gt;.", snippet("Routing is <b>fast</b>.", terms))
+
+	text := "w1 w2 w3 w4 w5 w6 w7 w8 w9 w10 w11 w12 routing w14 w15 w16 w17 w18 w19 w20 w21 w22 w23 w24 w25 w26 w27 w28 w29 w30 w31 w32 w33 w34 w35 w36 w37 w38 w39 w40"
+	s := snippet(text, terms)
+	assert.Equal(t, "…w5 w6 w7 w8 w9 w10 w11 w12 <mark>routing</mark> w14 w15 w16 w17 w18 w19 w20 w21 w22 w23 w24 w25 w26 w27 w28 w29 w30 w31 w32 w33 w34…", s)
+
+	assert.Equal(t, "no match here", snippet("no match here", terms))
+}
+
+func TestQueryTerms(t *testing.T) {
+	assert.Equal(t, []string{"rout", "packet"}, queryTerms("the routed routing packets"))
+	assert.Empty(t, queryTerms("  "))
+}
+
+func TestSearchPathReserved(t *testing.T) {
+	assert.True(t, isReservedPath("/search"))
+	assert.False(t, isReservedPath("/searching"))
+	assert.True(t, isReservedPath("/api/search"))
+}
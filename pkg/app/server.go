@@ -11,10 +11,15 @@ import (
 const (
 	HeaderPagebinVersion = "X-Pagebin-Version"
 	pathAPI              = "/api"
+	pathAssets           = "/_assets"
+	pathSitemap          = "/sitemap.xml"
+	pathSitemaps         = "/sitemaps/"
+	pathRobots           = "/robots.txt"
+	pathSearch           = "/search"
 )
 
 var (
-	reservedPaths = [...]string{pathAPI}
+	reservedPaths = [...]string{pathAPI, pathAssets, pathSitemap, pathSitemaps, pathRobots, pathSearch}
 )
 
 type Server struct {
@@ -31,19 +36,32 @@ func (s *Server) Shutdown(ctx context.Context) error {
 }
 
 func NewServer(rc *config.RuntimeConfig, service Service) *Server {
+	return &Server{rc, newApp(rc, service, newErrorHandler(service))}
+}
+
+// newApp routes the admin API and the rendered site.
+func newApp(rc *config.RuntimeConfig, service Service, errorHandler fiber.ErrorHandler) *fiber.App {
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
+		ErrorHandler:          errorHandler,
 	})
 	api := NewAdminAPI(service)
 	api.Register(app)
-	renderer := NewRenderer(service)
+	renderer := NewRenderer(rc, service)
+	app.Get(pathAssets+"/:name", renderer.asset)
+	app.Get(pathSitemap, renderer.sitemap)
+	app.Get(pathSitemaps+":name", renderer.sitemap)
+	app.Get(pathRobots, renderer.robots)
+	app.Get(pathSearch, renderer.search)
 	app.Get("*", renderer.render)
-	return &Server{rc, app}
+	return app
 }
 
+// isReservedPath reports whether a path is, or is under, a path served by pagebin itself. Paths only share a prefix
+// with a reserved path when it ends at a segment, so that pages such as /robots stay available.
 func isReservedPath(path string) bool {
 	for _, p := range reservedPaths {
-		if strings.HasPrefix(path, p) {
+		if path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/") {
 			return true
 		}
 	}
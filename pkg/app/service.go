@@ -2,6 +2,9 @@ package app
 
 import (
 	"context"
+	"io/fs"
+	"slices"
+	"time"
 
 	"github.com/aarongodin/pagebin/pkg/config"
 	"github.com/aarongodin/pagebin/pkg/core"
@@ -12,15 +15,41 @@ import (
 // Service contains the shared business logic for the app
 type Service interface {
 	GetSite(ctx context.Context) (core.Site, error)
-	UpdateSite(ctx context.Context, title string) (core.Site, error)
+	UpdateSite(ctx context.Context, update core.SiteUpdate) (core.Site, error)
 	GetVersion(ctx context.Context, uid ulid.ULID) (core.Version, error)
+	GetVersions(ctx context.Context, start *ulid.ULID) ([]core.Version, *ulid.ULID, error)
+	DiffVersions(ctx context.Context, a ulid.ULID, b ulid.ULID) (core.VersionDiff, error)
 	GetPages(ctx context.Context, start *ulid.ULID) ([]core.Page, *ulid.ULID, error)
 	GetPage(ctx context.Context, uid ulid.ULID) (core.Page, error)
-	PutPage(ctx context.Context, uid *ulid.ULID, page core.WritablePage, content []byte) (created core.Page, txErr error)
-	DeletePage(ctx context.Context, uid ulid.ULID) error
+	GetBlob(ctx context.Context, uid ulid.ULID) (core.Blob, error)
+	PutPage(ctx context.Context, target *core.TargetVersion, uid *ulid.ULID, page core.WritablePage, content []byte) (created core.Page, txErr error)
+	DeletePage(ctx context.Context, target *core.TargetVersion, uid ulid.ULID) error
+	Search(ctx context.Context, target *core.TargetVersion, query string, limit int, page int) (core.SearchResults, error)
+	Export(ctx context.Context, options ExportOptions, w ExportWriter) (core.ExportReport, error)
+	Import(ctx context.Context, fsys fs.FS, options ImportOptions) (core.ImportReport, error)
+	GetRedirects(ctx context.Context, target *core.TargetVersion) ([]core.Redirect, error)
+	CreateRedirect(ctx context.Context, target *core.TargetVersion, redirect core.Redirect) (core.Redirect, error)
+	DeleteRedirect(ctx context.Context, target *core.TargetVersion, uid ulid.ULID) error
+	Publish(ctx context.Context, publishedBy string, message string) (core.Site, error)
+	Rollback(ctx context.Context, versionUID ulid.ULID, publishedBy string) (core.Site, error)
+	CreateDraft(ctx context.Context, name string, createdBy string) (core.Site, error)
+	DeleteDraft(ctx context.Context, name string) (core.Site, error)
+	MergeDraft(ctx context.Context, name string, publishedBy string, message string) (core.Site, error)
+	GetReleases(ctx context.Context, start *ulid.ULID) ([]core.Release, *ulid.ULID, error)
+	GetSchedules(ctx context.Context, start *ulid.ULID) ([]core.Schedule, *ulid.ULID, error)
+	CreateSchedule(ctx context.Context, runAt time.Time, createdBy string, message string) (core.Schedule, error)
+	DeleteSchedule(ctx context.Context, uid ulid.ULID) error
+	CollectGarbage(ctx context.Context, dryRun bool) (core.GCReport, error)
+	GetPreviews(ctx context.Context, start *ulid.ULID) ([]core.Preview, *ulid.ULID, error)
+	CreatePreview(ctx context.Context, version string, ttl time.Duration, createdBy string) (core.Preview, error)
+	DeletePreview(ctx context.Context, uid ulid.ULID) error
+	ResolvePreview(ctx context.Context, token string) (core.Preview, error)
 	VersionManager() VersionManager
 	ThemeManager() ThemeManager
 	ContentManager() ContentManager
+	RenderCache() RenderCache
+	Collections() Collections
+	Scheduler() Scheduler
 }
 
 type Svc struct {
@@ -28,6 +57,12 @@ type Svc struct {
 	vm    VersionManager
 	tm    ThemeManager
 	cm    ContentManager
+	rdc   RenderCache
+	col   Collections
+	sched Scheduler
+	gc    garbageCollector
+	ps    previewSigner
+	rc    *config.RuntimeConfig
 }
 
 func (s *Svc) VersionManager() VersionManager {
@@ -42,33 +77,100 @@ func (s *Svc) ContentManager() ContentManager {
 	return s.cm
 }
 
+func (s *Svc) RenderCache() RenderCache {
+	return s.rdc
+}
+
+func (s *Svc) Collections() Collections {
+	return s.col
+}
+
+func (s *Svc) Scheduler() Scheduler {
+	return s.sched
+}
+
 func (s *Svc) GetSite(ctx context.Context) (core.Site, error) {
 	return s.store.Sites().GetSite(ctx)
 }
 
-func (s *Svc) UpdateSite(ctx context.Context, title string) (core.Site, error) {
-	return s.store.Sites().UpdateSite(ctx, title)
+func (s *Svc) UpdateSite(ctx context.Context, update core.SiteUpdate) (core.Site, error) {
+	if update.URL != nil {
+		if err := validateSiteURL(*update.URL); err != nil {
+			return core.Site{}, err
+		}
+	}
+	if update.Feeds != nil {
+		if err := validateFeeds(*update.Feeds); err != nil {
+			return core.Site{}, err
+		}
+	}
+	site, err := s.store.Sites().UpdateSite(ctx, update)
+	if err != nil {
+		return site, err
+	}
+	s.RenderCache().Purge()
+	return site, nil
 }
 
 func (s *Svc) GetPage(ctx context.Context, uid ulid.ULID) (core.Page, error) {
 	return s.store.Pages().GetPage(ctx, uid)
 }
 
-// func (s *Svc) GetPages(ctx context.Context, cursor *ulid.ULID) ([]core.Page, *ulid.ULID, error) {
-// 	return s.store
-// }
+func (s *Svc) GetBlob(ctx context.Context, uid ulid.ULID) (core.Blob, error) {
+	return s.store.Blobs().GetBlob(ctx, uid)
+}
+
+func (s *Svc) GetPages(ctx context.Context, start *ulid.ULID) ([]core.Page, *ulid.ULID, error) {
+	return s.store.Pages().GetPages(ctx, start)
+}
 
 func (s *Svc) GetVersion(ctx context.Context, uid ulid.ULID) (core.Version, error) {
 	return s.store.Versions().GetVersion(ctx, uid)
 }
 
-func (s *Svc) PutPage(ctx context.Context, uid *ulid.ULID, write core.WritablePage, content []byte) (created core.Page, txErr error) {
+func (s *Svc) GetVersions(ctx context.Context, start *ulid.ULID) ([]core.Version, *ulid.ULID, error) {
+	return s.store.Versions().GetVersions(ctx, start)
+}
+
+// DiffVersions returns the changes that turn version a into version b.
+func (s *Svc) DiffVersions(ctx context.Context, a ulid.ULID, b ulid.ULID) (diff core.VersionDiff, txErr error) {
+	ctx, err := s.store.StartTx(ctx, false)
+	if err != nil {
+		return diff, err
+	}
+	defer func() {
+		txErr = s.store.EndTx(ctx, txErr)
+	}()
+	from, err := s.GetVersion(ctx, a)
+	if err != nil {
+		return diff, err
+	}
+	to, err := s.GetVersion(ctx, b)
+	if err != nil {
+		return diff, err
+	}
+	return versionDiffer{s.store}.diff(ctx, from, to)
+}
+
+// PutPage creates or updates a page in the target version, which must be the next version or a draft.
+func (s *Svc) PutPage(ctx context.Context, target *core.TargetVersion, uid *ulid.ULID, write core.WritablePage, content []byte) (created core.Page, txErr error) {
+	if !target.IsWritable() {
+		return created, core.ErrInvalidVersion.New("version %s is not writable", target.UID().String())
+	}
+	if err := validatePath(write.Path); err != nil {
+		return created, err
+	}
+	if err := validateContent(write.ContentType, content); err != nil {
+		return created, err
+	}
 	ctx, err := s.store.StartTx(ctx, true)
 	if err != nil {
 		return created, err
 	}
 	defer func() {
-		txErr = s.store.EndTx(ctx, txErr)
+		if txErr = s.store.EndTx(ctx, txErr); txErr == nil {
+			s.purgeVersion(target.UID())
+		}
 	}()
 
 	var page *core.Page
@@ -86,51 +188,51 @@ func (s *Svc) PutPage(ctx context.Context, uid *ulid.ULID, write core.WritablePa
 	}
 
 	if page == nil {
-		p, err := s.createPage(ctx, write, content)
+		p, err := s.createPage(ctx, target.UID(), write, content, time.Time{})
 		if err != nil {
 			return created, err
 		}
 		page = &p
 	} else {
-		p, err := s.updatePage(ctx, *page, write, content)
+		p, err := s.updatePage(ctx, target.UID(), *page, write, content)
 		if err != nil {
 			return created, err
 		}
 		page = &p
 	}
 
-	if err := s.VersionManager().SetPage(previousPath, write.Path, page.UID); err != nil {
+	if err := s.indexPage(ctx, target.UID(), uid, *page, content); err != nil {
+		return created, err
+	}
+	if target.IsNext() {
+		if err := s.VersionManager().SetPage(previousPath, write.Path, page.UID); err != nil {
+			return created, err
+		}
+	}
+	if err := s.redirectPage(ctx, target, previousPath, write.Path); err != nil {
 		return created, err
 	}
 
 	return *page, nil
 }
 
-func (s *Svc) createPage(ctx context.Context, write core.WritablePage, content []byte) (core.Page, error) {
+func (s *Svc) createPage(ctx context.Context, versionUID ulid.ULID, write core.WritablePage, content []byte, createdAt time.Time) (core.Page, error) {
 	contentBlob, err := s.store.Blobs().CreateBlob(ctx, content)
 	if err != nil {
 		return core.Page{}, err
 	}
-	site, err := s.GetSite(ctx)
+	page, err := s.store.Pages().PutPage(ctx, nil, write, contentBlob.UID, createdAt)
 	if err != nil {
 		return core.Page{}, err
 	}
-	page, err := s.store.Pages().PutPage(ctx, nil, write, contentBlob.UID)
-	if err != nil {
-		return core.Page{}, err
-	}
-	if _, err = s.store.Versions().SetPage(ctx, site.NextVersion, "", page.Path, page.UID); err != nil {
+	if _, err = s.store.Versions().SetPage(ctx, versionUID, "", page.Path, page.UID); err != nil {
 		return page, err
 	}
 	return page, nil
 }
 
-func (s *Svc) updatePage(ctx context.Context, current core.Page, write core.WritablePage, content []byte) (core.Page, error) {
+func (s *Svc) updatePage(ctx context.Context, versionUID ulid.ULID, current core.Page, write core.WritablePage, content []byte) (core.Page, error) {
 	createPage := false
-	site, err := s.GetSite(ctx)
-	if err != nil {
-		return core.Page{}, err
-	}
 	versions, err := s.store.Versions().GetPageVersions(ctx, current.UID)
 	if err != nil {
 		return core.Page{}, err
@@ -140,7 +242,7 @@ func (s *Svc) updatePage(ctx context.Context, current core.Page, write core.Writ
 		return core.Page{}, core.ErrUnknown.New("expected page %s to belong to at least one version", current.UID.String())
 	case 1:
 		v := versions.ToSlice()[0]
-		if v != site.NextVersion {
+		if v != versionUID {
 			createPage = true
 		}
 	default:
@@ -148,12 +250,13 @@ func (s *Svc) updatePage(ctx context.Context, current core.Page, write core.Writ
 	}
 
 	if createPage {
-		if versions.Contains(site.NextVersion) {
-			// this is where I would reconcile that the next version should not have the old UID
-			// probably do soemthing like:
-			// s.store.Versions().RemovePage(ctx, site.NextVersion, current.UID)
+		if versions.Contains(versionUID) {
+			// the page is shared with another version, so the target version gets a copy and drops the original
+			if _, err := s.store.Versions().UnsetPage(ctx, versionUID, current.Path, current.UID); err != nil {
+				return core.Page{}, err
+			}
 		}
-		return s.createPage(ctx, write, content)
+		return s.createPage(ctx, versionUID, write, content, current.CreatedAt)
 	}
 
 	blob, err := s.store.Blobs().GetBlob(ctx, current.Content)
@@ -166,9 +269,10 @@ func (s *Svc) updatePage(ctx context.Context, current core.Page, write core.Writ
 		if blob, err = s.store.Blobs().UpdateBlob(ctx, blob.UID, content); err != nil {
 			return core.Page{}, err
 		}
+		s.ContentManager().Refresh(blob.UID)
 	}
 
-	page, err := s.store.Pages().PutPage(ctx, &current.UID, write, blob.UID)
+	page, err := s.store.Pages().PutPage(ctx, &current.UID, write, blob.UID, current.CreatedAt)
 	if err != nil {
 		return core.Page{}, err
 	}
@@ -176,40 +280,334 @@ func (s *Svc) updatePage(ctx context.Context, current core.Page, write core.Writ
 	return page, nil
 }
 
-func (s *Svc) DeletePage(ctx context.Context, uid ulid.ULID) (txErr error) {
+// DeletePage removes a page from the target version, which must be the next version or a draft.
+func (s *Svc) DeletePage(ctx context.Context, target *core.TargetVersion, uid ulid.ULID) (txErr error) {
+	if !target.IsWritable() {
+		return core.ErrInvalidVersion.New("version %s is not writable", target.UID().String())
+	}
 	ctx, err := s.store.StartTx(ctx, true)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		txErr = s.store.EndTx(ctx, txErr)
+		if txErr = s.store.EndTx(ctx, txErr); txErr == nil {
+			s.purgeVersion(target.UID())
+		}
 	}()
-	site, err := s.GetSite(ctx)
+	page, err := s.GetPage(ctx, uid)
 	if err != nil {
 		return err
 	}
-	page, err := s.GetPage(ctx, uid)
+	if _, err := s.store.Versions().UnsetPage(ctx, target.UID(), page.Path, page.UID); err != nil {
+		return err
+	}
+	if err := s.store.Search().DeleteDocument(ctx, target.UID(), page.UID); err != nil {
+		return err
+	}
+	if target.IsNext() {
+		if err := s.VersionManager().UnsetPage(page.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeVersion drops the rendered output, compiled routes and collections cached for a version. Writes call it once
+// their transaction commits, since a render between the purge and the commit would cache the previous state again.
+func (s *Svc) purgeVersion(uid ulid.ULID) {
+	s.RenderCache().PurgeVersion(uid)
+	s.VersionManager().PurgeVersion(uid)
+	s.Collections().PurgeVersion(uid)
+}
+
+// Publish makes the next version the current version and clones a new next version from it. When the current version
+// changed since the next version was cloned, for example by merging a draft, the changes in the next version are
+// merged into the current version instead. The compiled version and theme are swapped once the transaction commits.
+func (s *Svc) Publish(ctx context.Context, publishedBy string, message string) (core.Site, error) {
+	return s.promote(ctx, nil, publishedBy, message)
+}
+
+// publishSchedule publishes the next version only when it is still the version the schedule was created for.
+func (s *Svc) publishSchedule(ctx context.Context, schedule core.Schedule) (core.Site, error) {
+	return s.promote(ctx, &schedule.Version, schedule.CreatedBy, schedule.Message)
+}
+
+func (s *Svc) promote(ctx context.Context, versionUID *ulid.ULID, publishedBy string, message string) (core.Site, error) {
+	site, err := s.publish(ctx, versionUID, publishedBy, message)
+	if err != nil {
+		return core.Site{}, err
+	}
+	if err := s.reload(ctx, site); err != nil {
+		return core.Site{}, err
+	}
+	return site, nil
+}
+
+func (s *Svc) publish(ctx context.Context, versionUID *ulid.ULID, publishedBy string, message string) (site core.Site, txErr error) {
+	ctx, err := s.store.StartTx(ctx, true)
+	if err != nil {
+		return site, err
+	}
+	defer func() {
+		txErr = s.store.EndTx(ctx, txErr)
+	}()
+	current, err := s.GetSite(ctx)
+	if err != nil {
+		return site, err
+	}
+	if versionUID != nil && *versionUID != current.NextVersion {
+		return site, core.ErrInvalidVersion.New("version %s is no longer the next version", versionUID.String())
+	}
+	published, err := s.merge(ctx, current.Version, current.NextVersion, publishedBy)
+	if err != nil {
+		return site, err
+	}
+	if _, err := s.store.Versions().MarkPublished(ctx, published, publishedBy, message); err != nil {
+		return site, err
+	}
+	nextVersion, err := s.store.Versions().Clone(ctx, published, publishedBy)
+	if err != nil {
+		return site, err
+	}
+	if _, err := s.store.Releases().CreateRelease(ctx, core.ReleaseKindPublish, published, current.Version); err != nil {
+		return site, err
+	}
+	return s.store.Sites().SetVersions(ctx, published, nextVersion.UID)
+}
+
+// Rollback makes a previously stored version the current version. The next version is left untouched, and the
+// release is recorded so that the version that was live before the rollback can be restored the same way.
+func (s *Svc) Rollback(ctx context.Context, versionUID ulid.ULID, publishedBy string) (core.Site, error) {
+	site, err := s.rollback(ctx, versionUID, publishedBy)
+	if err != nil {
+		return core.Site{}, err
+	}
+	if err := s.reload(ctx, site); err != nil {
+		return core.Site{}, err
+	}
+	return site, nil
+}
+
+func (s *Svc) rollback(ctx context.Context, versionUID ulid.ULID, publishedBy string) (site core.Site, txErr error) {
+	ctx, err := s.store.StartTx(ctx, true)
+	if err != nil {
+		return site, err
+	}
+	defer func() {
+		txErr = s.store.EndTx(ctx, txErr)
+	}()
+	current, err := s.GetSite(ctx)
+	if err != nil {
+		return site, err
+	}
+	switch versionUID {
+	case current.Version:
+		return site, core.ErrInvalidVersion.New("version %s is already the current version", versionUID.String())
+	case current.NextVersion:
+		return site, core.ErrInvalidVersion.New("version %s is the next version; publish it instead", versionUID.String())
+	}
+	for name, draft := range current.Drafts {
+		if draft == versionUID {
+			return site, core.ErrInvalidVersion.New("version %s is draft \"%s\"; merge it instead", versionUID.String(), name)
+		}
+	}
+	version, err := s.GetVersion(ctx, versionUID)
+	if err != nil {
+		return site, err
+	}
+	if err := s.checkVersion(ctx, version); err != nil {
+		return site, err
+	}
+	if _, err := s.store.Versions().MarkPublished(ctx, version.UID, publishedBy, ""); err != nil {
+		return site, err
+	}
+	if _, err := s.store.Releases().CreateRelease(ctx, core.ReleaseKindRollback, version.UID, current.Version); err != nil {
+		return site, err
+	}
+	return s.store.Sites().SetVersions(ctx, version.UID, current.NextVersion)
+}
+
+// checkVersion returns an error when any page, theme or blob referenced by the version no longer exists.
+func (s *Svc) checkVersion(ctx context.Context, version core.Version) error {
+	for path, pageUID := range version.Pages {
+		page, err := s.GetPage(ctx, pageUID)
+		if err != nil {
+			return core.ErrVersionIncomplete.Wrap(err, "page %s at path %s is missing", pageUID.String(), path)
+		}
+		if _, err := s.store.Blobs().GetBlob(ctx, page.Content); err != nil {
+			return core.ErrVersionIncomplete.Wrap(err, "content for page %s is missing", pageUID.String())
+		}
+	}
+	theme, err := s.store.Themes().GetTheme(ctx, version.Theme)
+	if err != nil {
+		return core.ErrVersionIncomplete.Wrap(err, "theme %s is missing", version.Theme.String())
+	}
+	blobs := slices.Concat(theme.CSSAssets, theme.JSAssets)
+	for _, templateUID := range theme.Templates {
+		blobs = append(blobs, templateUID)
+	}
+	for _, blobUID := range blobs {
+		if _, err := s.store.Blobs().GetBlob(ctx, blobUID); err != nil {
+			return core.ErrVersionIncomplete.Wrap(err, "blob %s for theme %s is missing", blobUID.String(), theme.UID.String())
+		}
+	}
+	return nil
+}
+
+func (s *Svc) GetReleases(ctx context.Context, start *ulid.ULID) ([]core.Release, *ulid.ULID, error) {
+	return s.store.Releases().GetReleases(ctx, start)
+}
+
+// reload compiles the versions and theme referenced by the site and swaps them into the managers.
+func (s *Svc) reload(ctx context.Context, site core.Site) error {
+	version, err := s.GetVersion(ctx, site.Version)
 	if err != nil {
 		return err
 	}
-	if _, err := s.store.Versions().UnsetPage(ctx, site.NextVersion, page.Path, page.UID); err != nil {
+	if err := s.VersionManager().Load(ctx, site.Version, site.NextVersion); err != nil {
 		return err
 	}
-	if err := s.VersionManager().UnsetPage(page.Path); err != nil {
+	if err := s.ThemeManager().Load(ctx, version.Theme); err != nil {
 		return err
 	}
+	s.RenderCache().Purge()
+	s.Collections().Purge()
 	return nil
 }
 
+func (s *Svc) GetSchedules(ctx context.Context, start *ulid.ULID) ([]core.Schedule, *ulid.ULID, error) {
+	return s.store.Schedules().GetSchedules(ctx, start)
+}
+
+// CreateSchedule schedules the current next version to be published at runAt.
+func (s *Svc) CreateSchedule(ctx context.Context, runAt time.Time, createdBy string, message string) (core.Schedule, error) {
+	if runAt.Before(time.Now()) {
+		return core.Schedule{}, core.ErrInvalidSchedule.New("runAt must be in the future")
+	}
+	site, err := s.GetSite(ctx)
+	if err != nil {
+		return core.Schedule{}, err
+	}
+	schedule, err := s.store.Schedules().CreateSchedule(ctx, site.NextVersion, runAt, createdBy, message)
+	if err != nil {
+		return core.Schedule{}, err
+	}
+	s.Scheduler().Notify()
+	return schedule, nil
+}
+
+func (s *Svc) DeleteSchedule(ctx context.Context, uid ulid.ULID) error {
+	if err := s.store.Schedules().DeleteSchedule(ctx, uid); err != nil {
+		return err
+	}
+	s.Scheduler().Notify()
+	return nil
+}
+
+// CollectGarbage deletes versions outside of the retention policy and everything only they referenced. With dryRun,
+// the report lists what would be deleted without changing anything.
+func (s *Svc) CollectGarbage(ctx context.Context, dryRun bool) (core.GCReport, error) {
+	report, err := s.gc.collect(ctx, dryRun)
+	if err != nil {
+		return report, err
+	}
+	if !dryRun {
+		for _, uid := range report.Blobs {
+			s.ContentManager().Refresh(uid)
+		}
+		for _, uid := range report.Versions {
+			s.VersionManager().PurgeVersion(uid)
+		}
+	}
+	return report, nil
+}
+
+func (s *Svc) GetPreviews(ctx context.Context, start *ulid.ULID) ([]core.Preview, *ulid.ULID, error) {
+	previews, cursor, err := s.store.Previews().GetPreviews(ctx, start)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range previews {
+		previews[i].Token = s.ps.sign(previews[i])
+	}
+	return previews, cursor, nil
+}
+
+// CreatePreview mints a preview of version, which is either "next", a draft name or a version UID. A zero ttl uses the default
+// from the runtime config.
+func (s *Svc) CreatePreview(ctx context.Context, version string, ttl time.Duration, createdBy string) (core.Preview, error) {
+	site, err := s.GetSite(ctx)
+	if err != nil {
+		return core.Preview{}, err
+	}
+	if _, isDraft := site.Drafts[version]; version != "next" && !isDraft {
+		uid, err := ulid.Parse(version)
+		if err != nil {
+			return core.Preview{}, core.ErrInvalidVersion.New("version \"%s\" invalid. Specify either \"next\", a draft name or a version UID", version)
+		}
+		if _, err := s.GetVersion(ctx, uid); err != nil {
+			return core.Preview{}, err
+		}
+	}
+	if ttl <= 0 {
+		ttl = s.rc.PreviewDefaultTTL
+	}
+	preview, err := s.store.Previews().CreatePreview(ctx, version, time.Now().Add(ttl), createdBy)
+	if err != nil {
+		return core.Preview{}, err
+	}
+	preview.Token = s.ps.sign(preview)
+	return preview, nil
+}
+
+func (s *Svc) DeletePreview(ctx context.Context, uid ulid.ULID) error {
+	return s.store.Previews().DeletePreview(ctx, uid)
+}
+
+// ResolvePreview returns the preview for a token that is correctly signed, has not expired and has not been deleted.
+func (s *Svc) ResolvePreview(ctx context.Context, token string) (core.Preview, error) {
+	uid, err := s.ps.verify(token)
+	if err != nil {
+		return core.Preview{}, err
+	}
+	preview, err := s.store.Previews().GetPreview(ctx, uid)
+	if err != nil {
+		return core.Preview{}, core.ErrInvalidPreview.Wrap(err, "preview revoked")
+	}
+	preview.Token = token
+	return preview, nil
+}
+
 func NewService(rc *config.RuntimeConfig, s store.Store) (Service, error) {
 	cm, err := NewCachedContentManager(rc, s.Blobs())
 	if err != nil {
 		return nil, err
 	}
-	return &Svc{
+	tm, err := NewThemeManager(s.Themes(), s.Blobs())
+	if err != nil {
+		return nil, err
+	}
+	ps, err := newPreviewSigner(rc)
+	if err != nil {
+		return nil, err
+	}
+	col, err := NewCollections(s.Versions(), s.Pages())
+	if err != nil {
+		return nil, err
+	}
+	svc := &Svc{
 		store: s,
 		vm:    NewVersionManager(s.Versions()),
-		tm:    NewThemeManager(s.Themes(), s.Blobs()),
+		tm:    tm,
 		cm:    cm,
-	}, nil
+		rdc:   NewRenderCache(rc),
+		col:   col,
+		gc:    newGarbageCollector(rc, s),
+		ps:    ps,
+		rc:    rc,
+	}
+	if svc.sched, err = NewScheduler(rc, s.Schedules(), svc.publishSchedule); err != nil {
+		return nil, err
+	}
+	return svc, nil
 }
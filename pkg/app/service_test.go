@@ -0,0 +1,269 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/config"
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/aarongodin/pagebin/pkg/store"
+	"github.com/gofiber/fiber/v2"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestService creates a provisioned and loaded service backed by a database in a temporary directory.
+func newTestService(t *testing.T, configure ...func(rc *config.RuntimeConfig)) *Svc {
+	t.Helper()
+	ctx := context.Background()
+	dir := t.TempDir()
+	rc := &config.RuntimeConfig{
+		DatabaseFile:          filepath.Join(dir, "pagebin.data"),
+		BlobBackend:           "localfs",
+		BlobLocalFSRootDir:    filepath.Join(dir, "content"),
+		ContentCacheSize:      10,
+		RenderCacheMaxBytes:   1 << 20,
+		SchedulerMissedPolicy: config.SchedulerMissedPolicyRun,
+		GCRetainVersions:      10,
+		GCRetainAge:           720 * time.Hour,
+		PreviewSecret:         "test-secret",
+		PreviewDefaultTTL:     time.Hour,
+	}
+	for _, fn := range configure {
+		fn(rc)
+	}
+	st, err := store.NewStore(rc)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		st.Close(ctx)
+	})
+	require.NoError(t, Provision(ctx, st))
+	svc, err := NewService(rc, st)
+	require.NoError(t, err)
+	s := svc.(*Svc)
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	require.NoError(t, s.reload(ctx, site))
+	return s
+}
+
+// nextTarget returns the target of the next version of the site.
+func nextTarget(t *testing.T, s *Svc) *core.TargetVersion {
+	t.Helper()
+	site, err := s.GetSite(context.Background())
+	require.NoError(t, err)
+	return core.NewNextTargetVersion(site.NextVersion)
+}
+
+// putTestPage writes a markdown page to a version.
+func putTestPage(t *testing.T, s *Svc, target *core.TargetVersion, uid *ulid.ULID, path string, content string) core.Page {
+	t.Helper()
+	page, err := s.PutPage(context.Background(), target, uid, core.WritablePage{
+		Title:        path,
+		Path:         path,
+		TemplateName: "default",
+		Tags:         []string{},
+		ContentType:  core.ContentTypeMarkdown,
+	}, []byte(content))
+	require.NoError(t, err)
+	return page
+}
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	putTestPage(t, s, nextTarget(t, s), nil, "/a", "# A")
+	first := publishTest(t, s)
+	putTestPage(t, s, nextTarget(t, s), nil, "/b", "# B")
+	second := publishTest(t, s)
+
+	site, err := s.Rollback(ctx, first.Version, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, first.Version, site.Version)
+	assert.Equal(t, second.NextVersion, site.NextVersion, "the next version is left untouched")
+	version, err := s.GetVersion(ctx, first.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", version.PublishedBy)
+	releases, _, err := s.GetReleases(ctx, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, releases)
+	assert.Equal(t, core.ReleaseKindRollback, releases[0].Kind)
+	assert.Equal(t, first.Version, releases[0].Version)
+	assert.Equal(t, second.Version, releases[0].PreviousVersion)
+	current := core.NewCurrentTargetVersion(site.Version)
+	_, err = s.VersionManager().GetByPath(ctx, current, "/b", acquireParams())
+	assert.True(t, errorx.IsOfType(err, core.ErrPageNotFound), "the live site serves the rolled back version")
+
+	site, err = s.Rollback(ctx, releases[0].PreviousVersion, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, second.Version, site.Version, "the version live before the rollback is restored")
+	_, err = s.VersionManager().GetByPath(ctx, core.NewCurrentTargetVersion(site.Version), "/b", acquireParams())
+	assert.NoError(t, err)
+}
+
+func TestRollbackRefusesLiveVersions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	draft := createTestDraft(t, s, "feature")
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+
+	for desc, uid := range map[string]ulid.ULID{
+		"current": site.Version,
+		"next":    site.NextVersion,
+		"draft":   draft.UID(),
+	} {
+		_, err := s.Rollback(ctx, uid, "admin")
+		assert.True(t, errorx.IsOfType(err, core.ErrInvalidVersion), desc)
+		unchanged, err := s.GetSite(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, site.Version, unchanged.Version, desc)
+	}
+}
+
+func TestRollbackRejectsIncompleteVersions(t *testing.T) {
+	testCases := map[string]func(s *Svc, page core.Page, version core.Version) error{
+		"missing page": func(s *Svc, page core.Page, _ core.Version) error {
+			return s.store.Pages().DeletePage(context.Background(), page.UID)
+		},
+		"missing blob": func(s *Svc, page core.Page, _ core.Version) error {
+			return s.store.Blobs().DeleteBlob(context.Background(), page.Content)
+		},
+		"missing theme": func(s *Svc, _ core.Page, version core.Version) error {
+			return s.store.Themes().DeleteTheme(context.Background(), version.Theme)
+		},
+	}
+	for desc, remove := range testCases {
+		t.Run(desc, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestService(t)
+			page := putTestPage(t, s, nextTarget(t, s), nil, "/a", "# A")
+			first := publishTest(t, s)
+			publishTest(t, s)
+			version, err := s.GetVersion(ctx, first.Version)
+			require.NoError(t, err)
+			require.NoError(t, remove(s, page, version))
+
+			_, err = s.Rollback(ctx, first.Version, "admin")
+			assert.True(t, errorx.IsOfType(err, core.ErrVersionIncomplete))
+		})
+	}
+}
+
+func TestPutPagePurgesCachesOnCommit(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	target := nextTarget(t, s)
+	cached := RenderedPage{Body: []byte("cached")}
+
+	s.RenderCache().Set(target.UID(), "/", cached)
+	missing := ulid.Make()
+	_, err := s.PutPage(ctx, target, &missing, core.WritablePage{Path: "/a", ContentType: core.ContentTypeMarkdown}, []byte("# A"))
+	require.Error(t, err)
+	_, ok := s.RenderCache().Get(target.UID(), "/")
+	assert.True(t, ok, "a failed write keeps the cache")
+
+	page := putTestPage(t, s, target, nil, "/a", "# A")
+	_, ok = s.RenderCache().Get(target.UID(), "/")
+	assert.False(t, ok, "a committed write purges the cache")
+
+	s.RenderCache().Set(target.UID(), "/", cached)
+	require.NoError(t, s.DeletePage(ctx, target, page.UID))
+	_, ok = s.RenderCache().Get(target.UID(), "/")
+	assert.False(t, ok)
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	previous, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	putTestPage(t, s, nextTarget(t, s), nil, "/a", "# A")
+
+	site, err := s.Publish(ctx, "admin", "add a")
+	require.NoError(t, err)
+	assert.Equal(t, previous.NextVersion, site.Version, "the next version becomes the current version")
+	assert.NotEqual(t, previous.NextVersion, site.NextVersion)
+	published, err := s.GetVersion(ctx, site.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", published.PublishedBy)
+	assert.Equal(t, "add a", published.Message)
+	assert.False(t, published.PublishedAt.IsZero())
+
+	next, err := s.GetVersion(ctx, site.NextVersion)
+	require.NoError(t, err)
+	assert.Equal(t, site.Version, next.Base, "the new next version is cloned from the published version")
+	assert.Equal(t, published.Pages, next.Pages)
+	assert.Equal(t, published.Theme, next.Theme)
+	assert.True(t, next.PublishedAt.IsZero())
+
+	releases, _, err := s.GetReleases(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, releases, 1)
+	assert.Equal(t, core.ReleaseKindPublish, releases[0].Kind)
+	assert.Equal(t, site.Version, releases[0].Version)
+	assert.Equal(t, previous.Version, releases[0].PreviousVersion)
+
+	_, err = s.VersionManager().GetByPath(ctx, core.NewCurrentTargetVersion(site.Version), "/a", acquireParams())
+	assert.NoError(t, err, "the published version is served")
+}
+
+func TestPublishConflict(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	site, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	current, err := s.GetVersion(ctx, site.Version)
+	require.NoError(t, err)
+	home := current.Pages["/"]
+
+	draft := createTestDraft(t, s, "feature")
+	putTestPage(t, s, draft, &home, "/", "# Draft home")
+	putTestPage(t, s, nextTarget(t, s), &home, "/", "# Next home")
+	before, err := s.MergeDraft(ctx, "feature", "", "")
+	require.NoError(t, err)
+	releases, _, err := s.GetReleases(ctx, nil)
+	require.NoError(t, err)
+
+	_, err = s.Publish(ctx, "", "")
+	assert.True(t, errorx.IsOfType(err, core.ErrMergeConflict))
+	after, err := s.GetSite(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, before.Version, after.Version, "a conflicting publish leaves the site unchanged")
+	assert.Equal(t, before.NextVersion, after.NextVersion)
+	unchanged, _, err := s.GetReleases(ctx, nil)
+	require.NoError(t, err)
+	assert.Len(t, unchanged, len(releases))
+}
+
+func TestPublishAPI(t *testing.T) {
+	s := newTestService(t)
+	previous, err := s.GetSite(context.Background())
+	require.NoError(t, err)
+	app := newApp(s.rc, s, newErrorHandler(s))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/publish", strings.NewReader(`{"publishedBy":"admin","message":"ship it"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	res, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var site core.Site
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&site))
+	assert.Equal(t, previous.NextVersion, site.Version)
+	published, err := s.GetVersion(context.Background(), site.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", published.PublishedBy)
+	assert.Equal(t, "ship it", published.Message)
+
+	res, err = app.Test(httptest.NewRequest(http.MethodPost, "/api/publish", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "the body is optional")
+}
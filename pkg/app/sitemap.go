@@ -0,0 +1,160 @@
+package app
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/gofiber/fiber/v2"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	// sitemapMaxURLs is the most URLs a sitemap may list. Larger sites are split into parts listed by a sitemap index.
+	sitemapMaxURLs   = 50000
+	sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+	contentTypeXML  = "application/xml; charset=utf-8"
+	contentTypeText = "text/plain; charset=utf-8"
+)
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// sitemapPages are the pages of a version that are listed in its sitemap.
+func sitemapPages(pages []core.Page) []core.Page {
+	listed := make([]core.Page, 0, len(pages))
+	for _, page := range pages {
+		if !page.NoIndex {
+			listed = append(listed, page)
+		}
+	}
+	return listed
+}
+
+// encodeSitemap encodes the sitemap at /sitemap.xml, which lists every page when there are at most maxURLs pages and
+// is otherwise an index of the parts at /sitemaps/<n>.xml. Part 0 is /sitemap.xml, and parts start from 1. A part out
+// of range is not found.
+func encodeSitemap(base string, pages []core.Page, part int, maxURLs int) ([]byte, error) {
+	parts := (len(pages) + maxURLs - 1) / maxURLs
+	if part == 0 && parts > 1 {
+		index := sitemapIndex{XMLNS: sitemapNamespace}
+		for i := 1; i <= parts; i++ {
+			chunk := pages[(i-1)*maxURLs : min(i*maxURLs, len(pages))]
+			index.Sitemaps = append(index.Sitemaps, sitemapURL{
+				Loc:     base + pathSitemaps + strconv.Itoa(i) + ".xml",
+				LastMod: formatLastMod(lastModified(chunk)),
+			})
+		}
+		return encodeXML(index)
+	}
+	if part > 0 {
+		if part > parts || parts == 1 {
+			return nil, core.ErrPageNotFound.New("sitemap part %d not found", part)
+		}
+		pages = pages[(part-1)*maxURLs : min(part*maxURLs, len(pages))]
+	}
+	urlset := sitemapURLSet{XMLNS: sitemapNamespace, URLs: []sitemapURL{}}
+	for _, page := range pages {
+		urlset.URLs = append(urlset.URLs, sitemapURL{Loc: base + page.Path, LastMod: formatLastMod(page.UpdatedAt)})
+	}
+	return encodeXML(urlset)
+}
+
+func lastModified(pages []core.Page) time.Time {
+	var last time.Time
+	for _, page := range pages {
+		if page.UpdatedAt.After(last) {
+			last = page.UpdatedAt
+		}
+	}
+	return last
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// sitemap serves the sitemap of the target version, or one part of it.
+func (r renderer) sitemap(ctx *fiber.Ctx) error {
+	part := 0
+	if name := ctx.Params("name"); name != "" {
+		n, err := strconv.Atoi(strings.TrimSuffix(name, ".xml"))
+		if err != nil || n < 1 || !strings.HasSuffix(name, ".xml") {
+			return core.ErrPageNotFound.NewWithNoMessage()
+		}
+		part = n
+	}
+	return r.sendVersion(ctx, contentTypeXML, func(site core.Site, version ulid.ULID) ([]byte, error) {
+		collection, err := r.service.Collections().Query(ctx.Context(), version, PageQuery{Sort: SortPath})
+		if err != nil {
+			return nil, err
+		}
+		return encodeSitemap(siteURL(ctx, site), sitemapPages(collection.pages), part, sitemapMaxURLs)
+	})
+}
+
+// robots serves /robots.txt from the site settings, or allows every path and lists the sitemap by default.
+func (r renderer) robots(ctx *fiber.Ctx) error {
+	return r.sendVersion(ctx, contentTypeText, func(site core.Site, _ ulid.ULID) ([]byte, error) {
+		if site.Robots != "" {
+			return []byte(site.Robots), nil
+		}
+		return []byte("User-agent: *\nAllow: /\n\nSitemap: " + siteURL(ctx, site) + pathSitemap + "\n"), nil
+	})
+}
+
+// sendVersion serves a document generated from the target version. Documents of the current version are cached until
+// the next publish or change to the site settings, the same as pages, unless the site has no URL and their links come
+// from the Host header.
+func (r renderer) sendVersion(ctx *fiber.Ctx, contentType string, generate func(site core.Site, version ulid.ULID) ([]byte, error)) error {
+	site, err := r.service.GetSite(ctx.Context())
+	if err != nil {
+		return err
+	}
+	targetVersion, err := getTargetVersion(ctx, r.service, false)
+	if err != nil {
+		return err
+	}
+	cacheable := targetVersion.IsCurrent() && len(ctx.Get(HeaderPagebinVersion)) == 0
+	cacheKey := renderCacheKey(ctx)
+	if cacheable {
+		ctx.Set(fiber.HeaderCacheControl, r.rc.CacheControlPages)
+		if rendered, ok := r.service.RenderCache().Get(targetVersion.UID(), cacheKey); ok {
+			return r.send(ctx, rendered)
+		}
+	} else {
+		ctx.Set(fiber.HeaderCacheControl, r.rc.CacheControlPreviews)
+	}
+	rendered := RenderedPage{ContentType: contentType}
+	if targetVersion.IsCurrent() {
+		rendered.LastModified = site.UpdatedAt
+	}
+	if rendered.Body, err = generate(site, targetVersion.UID()); err != nil {
+		return err
+	}
+	rendered.ETag = bodyETag(rendered.Body)
+	if cacheable && siteURLCacheable(site) {
+		r.service.RenderCache().Set(targetVersion.UID(), cacheKey, rendered)
+	}
+	return r.send(ctx, rendered)
+}
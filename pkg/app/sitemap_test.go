@@ -0,0 +1,66 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/joomcode/errorx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSitemapPages() []core.Page {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+	return []core.Page{
+		{Path: "/", UpdatedAt: day(1)},
+		{Path: "/a", UpdatedAt: day(4)},
+		{Path: "/draft", NoIndex: true, UpdatedAt: day(9)},
+		{Path: "/b", UpdatedAt: day(2)},
+	}
+}
+
+func TestEncodeSitemap(t *testing.T) {
+	pages := sitemapPages(testSitemapPages())
+	require.Len(t, pages, 3)
+
+	body, err := encodeSitemap("https://example.com", pages, 0, 10)
+	require.NoError(t, err)
+	out := string(body)
+	assert.Contains(t, out, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	assert.Contains(t, out, "<url><loc>https://example.com/a</loc><lastmod>2024-03-04T12:00:00Z</lastmod></url>")
+	assert.NotContains(t, out, "/draft")
+
+	_, err = encodeSitemap("https://example.com", pages, 1, 10)
+	assert.True(t, errorx.IsNotFound(err), "a sitemap that is not split has no parts")
+}
+
+func TestEncodeSitemapIndex(t *testing.T) {
+	pages := sitemapPages(testSitemapPages())
+
+	body, err := encodeSitemap("https://example.com", pages, 0, 2)
+	require.NoError(t, err)
+	out := string(body)
+	assert.Contains(t, out, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	assert.Contains(t, out, "<sitemap><loc>https://example.com/sitemaps/1.xml</loc><lastmod>2024-03-04T12:00:00Z</lastmod></sitemap>")
+	assert.Contains(t, out, "<sitemap><loc>https://example.com/sitemaps/2.xml</loc><lastmod>2024-03-02T12:00:00Z</lastmod></sitemap>")
+
+	body, err = encodeSitemap("https://example.com", pages, 2, 2)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "<loc>https://example.com/b</loc>")
+	assert.NotContains(t, string(body), "<loc>https://example.com/a</loc>")
+
+	_, err = encodeSitemap("https://example.com", pages, 3, 2)
+	assert.True(t, errorx.IsNotFound(err))
+}
+
+func TestIsReservedPath(t *testing.T) {
+	for _, path := range []string{"/api", "/api/pages", "/_assets/a1b2.css", "/sitemap.xml", "/sitemaps/1.xml", "/robots.txt"} {
+		assert.True(t, isReservedPath(path), path)
+	}
+	for _, path := range []string{"/", "/apiary", "/_assetsx", "/sitemap.xml.bak", "/sitemaps", "/robots"} {
+		assert.False(t, isReservedPath(path), path)
+	}
+}
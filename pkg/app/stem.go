@@ -0,0 +1,212 @@
+package app
+
+// stopWords are common English words that are not indexed or searched for.
+var stopWords = map[string]struct{}{
+	"a": {}, "about": {}, "after": {}, "all": {}, "also": {}, "am": {}, "an": {}, "and": {}, "any": {}, "are": {},
+	"as": {}, "at": {}, "be": {}, "because": {}, "been": {}, "before": {}, "being": {}, "between": {}, "both": {},
+	"but": {}, "by": {}, "can": {}, "could": {}, "did": {}, "do": {}, "does": {}, "doing": {}, "during": {},
+	"each": {}, "few": {}, "for": {}, "from": {}, "further": {}, "had": {}, "has": {}, "have": {}, "having": {},
+	"he": {}, "her": {}, "here": {}, "hers": {}, "him": {}, "his": {}, "how": {}, "i": {}, "if": {}, "in": {},
+	"into": {}, "is": {}, "it": {}, "its": {}, "just": {}, "me": {}, "more": {}, "most": {}, "my": {}, "no": {},
+	"nor": {}, "not": {}, "of": {}, "off": {}, "on": {}, "once": {}, "only": {}, "or": {}, "other": {}, "our": {},
+	"ours": {}, "out": {}, "over": {}, "own": {}, "same": {}, "she": {}, "should": {}, "so": {}, "some": {},
+	"such": {}, "than": {}, "that": {}, "the": {}, "their": {}, "theirs": {}, "them": {}, "then": {}, "there": {},
+	"these": {}, "they": {}, "this": {}, "those": {}, "through": {}, "to": {}, "too": {}, "under": {}, "until": {},
+	"up": {}, "very": {}, "was": {}, "we": {}, "were": {}, "what": {}, "when": {}, "where": {}, "which": {},
+	"while": {}, "who": {}, "whom": {}, "why": {}, "will": {}, "with": {}, "would": {}, "you": {}, "your": {},
+	"yours": {},
+}
+
+type stemRule struct {
+	suffix      string
+	replacement string
+}
+
+var (
+	stemStep2 = []stemRule{
+		{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"}, {"izer", "ize"}, {"abli", "able"},
+		{"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+		{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}, {"aliti", "al"},
+		{"iviti", "ive"}, {"biliti", "ble"},
+	}
+	stemStep3 = []stemRule{
+		{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"}, {"ical", "ic"}, {"ful", ""}, {"ness", ""},
+	}
+	stemStep4 = []string{
+		"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment", "ent", "ion", "ou", "ism", "ate",
+		"iti", "ous", "ive", "ize",
+	}
+)
+
+// stem reduces an English word to its stem with the Porter stemming algorithm, so that words such as "connected" and
+// "connection" are indexed as the same term. Words that are not lowercase ASCII letters are returned unchanged.
+func stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	w := []byte(word)
+	w = stemStep1a(w)
+	w = stemStep1b(w)
+	if hasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		w[len(w)-1] = 'i'
+	}
+	w = replaceLongestSuffix(w, stemStep2, 0)
+	w = replaceLongestSuffix(w, stemStep3, 0)
+	w = stemStep4Remove(w)
+	w = stemStep5(w)
+	return string(w)
+}
+
+func stemStep1a(w []byte) []byte {
+	switch {
+	case hasSuffix(w, "sses"), hasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case hasSuffix(w, "ss"):
+		return w
+	case hasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func stemStep1b(w []byte) []byte {
+	if hasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+	var stem []byte
+	switch {
+	case hasSuffix(w, "ed") && hasVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case hasSuffix(w, "ing") && hasVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+	switch {
+	case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+		return append(stem, 'e')
+	case endsDoubleConsonant(stem):
+		if last := stem[len(stem)-1]; last != 'l' && last != 's' && last != 'z' {
+			return stem[:len(stem)-1]
+		}
+	case measure(stem) == 1 && endsCVC(stem):
+		return append(stem, 'e')
+	}
+	return stem
+}
+
+// replaceLongestSuffix replaces the longest suffix of w that has a rule when the measure of the rest of w is greater
+// than minMeasure.
+func replaceLongestSuffix(w []byte, rules []stemRule, minMeasure int) []byte {
+	match := -1
+	for i, rule := range rules {
+		if hasSuffix(w, rule.suffix) && (match < 0 || len(rule.suffix) > len(rules[match].suffix)) {
+			match = i
+		}
+	}
+	if match < 0 {
+		return w
+	}
+	stem := w[:len(w)-len(rules[match].suffix)]
+	if measure(stem) <= minMeasure {
+		return w
+	}
+	return append(stem, rules[match].replacement...)
+}
+
+func stemStep4Remove(w []byte) []byte {
+	longest := ""
+	for _, suffix := range stemStep4 {
+		if hasSuffix(w, suffix) && len(suffix) > len(longest) {
+			longest = suffix
+		}
+	}
+	if longest == "" {
+		return w
+	}
+	stem := w[:len(w)-len(longest)]
+	if measure(stem) <= 1 {
+		return w
+	}
+	if longest == "ion" && !hasSuffix(stem, "s") && !hasSuffix(stem, "t") {
+		return w
+	}
+	return stem
+}
+
+func stemStep5(w []byte) []byte {
+	if hasSuffix(w, "e") {
+		stem := w[:len(w)-1]
+		if m := measure(stem); m > 1 || m == 1 && !endsCVC(stem) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && endsDoubleConsonant(w) && hasSuffix(w, "l") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
+
+func hasSuffix(w []byte, suffix string) bool {
+	return len(w) >= len(suffix) && string(w[len(w)-len(suffix):]) == suffix
+}
+
+// isConsonant reports whether w[i] is a consonant. Y is a consonant unless it follows a consonant.
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel-consonant sequences in w.
+func measure(w []byte) int {
+	m := 0
+	vowel := false
+	for i := range w {
+		if isConsonant(w, i) {
+			if vowel {
+				m++
+			}
+			vowel = false
+		} else {
+			vowel = true
+		}
+	}
+	return m
+}
+
+func hasVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports whether w ends with a consonant, vowel and consonant, where the last consonant is not w, x or y.
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-3) || isConsonant(w, n-2) || !isConsonant(w, n-1) {
+		return false
+	}
+	last := w[n-1]
+	return last != 'w' && last != 'x' && last != 'y'
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStem(t *testing.T) {
+	testCases := map[string]string{
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"cats":           "cat",
+		"agreed":         "agre",
+		"feed":           "feed",
+		"plastered":      "plaster",
+		"motoring":       "motor",
+		"sing":           "sing",
+		"hopping":        "hop",
+		"falling":        "fall",
+		"filing":         "file",
+		"happy":          "happi",
+		"relational":     "relat",
+		"conditional":    "condit",
+		"generalization": "gener",
+		"hopefulness":    "hope",
+		"electrical":     "electr",
+		"adjustment":     "adjust",
+		"adoption":       "adopt",
+		"controll":       "control",
+		"connected":      "connect",
+		"connection":     "connect",
+		"go":             "go",
+		"café":           "café",
+		"2024":           "2024",
+	}
+	for word, expected := range testCases {
+		assert.Equal(t, expected, stem(word), word)
+	}
+}
@@ -0,0 +1,347 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aymerick/raymond"
+)
+
+const (
+	defaultDateFormat     = "January 2, 2006"
+	defaultTruncateSuffix = "…"
+	urlWildcardHashKey    = "wildcard"
+)
+
+var dateFormats = map[string]string{
+	"date":     time.DateOnly,
+	"datetime": time.DateTime,
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+}
+
+// helperRegistry holds the helpers installed on every compiled theme. Helpers are registered per template rather than
+// globally with raymond, and themes compiled before a helper is registered do not see it until they are loaded again.
+type helperRegistry struct {
+	mu      sync.RWMutex
+	helpers map[string]any
+}
+
+var templateHelpers = &helperRegistry{
+	helpers: map[string]any{
+		"formatDate": formatDateHelper,
+		"url":        urlHelper,
+		"truncate":   truncateHelper,
+		"slugify":    slugify,
+		"json":       jsonHelper,
+		"join":       joinHelper,
+		"eq":         eqHelper,
+		"ne":         neHelper,
+		"lt":         ltHelper,
+		"lte":        lteHelper,
+		"gt":         gtHelper,
+		"gte":        gteHelper,
+		"and":        andHelper,
+		"or":         orHelper,
+		"not":        notHelper,
+		"pages":      pagesHelper,
+	},
+}
+
+// themeHelpers are registered by each compiled theme and cannot be replaced.
+var themeHelpers = [...]string{"extend", "fill", "block", "asset"}
+
+// RegisterHelper adds a helper to every theme compiled afterwards. The helper must be a function with one return value,
+// as documented by raymond. Registering a name twice, or the name of a built-in helper, panics.
+func RegisterHelper(name string, helper any) {
+	t := reflect.TypeOf(helper)
+	if t == nil || t.Kind() != reflect.Func || t.NumOut() != 1 {
+		panic(fmt.Sprintf("helper \"%s\" must be a function with one return value", name))
+	}
+	for _, reserved := range themeHelpers {
+		if name == reserved {
+			panic(fmt.Sprintf("helper \"%s\" is reserved", name))
+		}
+	}
+	templateHelpers.mu.Lock()
+	defer templateHelpers.mu.Unlock()
+	if _, exists := templateHelpers.helpers[name]; exists {
+		panic(fmt.Sprintf("helper \"%s\" is already registered", name))
+	}
+	templateHelpers.helpers[name] = helper
+}
+
+func (r *helperRegistry) install(tpl *raymond.Template) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tpl.RegisterHelpers(r.helpers)
+}
+
+// formatDateHelper formats a time, or an RFC 3339 string, with the format hash argument. The format is either a Go
+// time layout or one of date, datetime, rfc3339 and rfc1123. The optional tz hash argument is an IANA time zone.
+//
+//	{{ formatDate version.publishedAt format="Jan 2, 2006" tz="America/Chicago" }}
+func formatDateHelper(value any, options *raymond.Options) string {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v != nil {
+			t = *v
+		}
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return v
+		}
+		t = parsed
+	}
+	if t.IsZero() {
+		return ""
+	}
+	if tz := options.HashStr("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			panic(fmt.Errorf("formatDate: unknown time zone \"%s\"", tz))
+		}
+		t = t.In(loc)
+	}
+	format := options.HashStr("format")
+	if named, ok := dateFormats[format]; ok {
+		format = named
+	} else if format == "" {
+		format = defaultDateFormat
+	}
+	return t.Format(format)
+}
+
+// urlHelper builds a URL from a page path, filling parameters and wildcards from the hash arguments. Hash arguments
+// that are not used by the path are added as the query string. An unnamed wildcard is filled by the wildcard argument.
+//
+//	{{ url "/blog/:slug" slug="hello-world" page=2 }} => /blog/hello-world?page=2
+func urlHelper(path string, options *raymond.Options) string {
+	hash := options.Hash()
+	used := make(map[string]bool, len(hash))
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		var key string
+		switch {
+		case strings.HasPrefix(segment, string(routeParamPrefix)):
+			key = segment[1:]
+		case strings.HasPrefix(segment, string(routeWildcardPrefix)):
+			key = segment[1:]
+			if key == "" {
+				key = urlWildcardHashKey
+			}
+		default:
+			continue
+		}
+		used[key] = true
+		value := raymond.Str(hash[key])
+		if segment[0] == routeWildcardPrefix {
+			parts := strings.Split(value, "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		} else {
+			segments[i] = url.PathEscape(value)
+		}
+	}
+	query := url.Values{}
+	for key, value := range hash {
+		if !used[key] {
+			query.Set(key, raymond.Str(value))
+		}
+	}
+	out := strings.Join(segments, "/")
+	if len(query) > 0 {
+		out += "?" + query.Encode()
+	}
+	return out
+}
+
+// truncateHelper shortens a string to at most length characters, including the suffix hash argument which defaults
+// to an ellipsis. The string is cut at the last space when there is one.
+//
+//	{{ truncate page.excerpt 140 suffix="..." }}
+func truncateHelper(value string, length int, options *raymond.Options) string {
+	if utf8.RuneCountInString(value) <= length {
+		return value
+	}
+	suffix := defaultTruncateSuffix
+	if s, ok := options.HashProp("suffix").(string); ok {
+		suffix = s
+	}
+	keep := length - utf8.RuneCountInString(suffix)
+	if keep <= 0 {
+		return string([]rune(suffix)[:length])
+	}
+	cut := string([]rune(value)[:keep])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, unicode.IsSpace) + suffix
+}
+
+// slugify lowercases a string and replaces every run of characters other than letters and digits with a dash.
+func slugify(value string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range value {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// jsonHelper encodes a value as JSON. HTML characters are escaped, so the output is safe inside a script element.
+func jsonHelper(value any) raymond.SafeString {
+	out, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Errorf("json: %w", err))
+	}
+	return raymond.SafeString(out)
+}
+
+// joinHelper joins the items of a list with a separator.
+//
+//	{{ join page.tags ", " }}
+func joinHelper(value any, sep string) string {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return raymond.Str(value)
+	}
+	items := make([]string, v.Len())
+	for i := range items {
+		items[i] = raymond.Str(v.Index(i).Interface())
+	}
+	return strings.Join(items, sep)
+}
+
+func eqHelper(a any, b any) bool {
+	c, ok := compare(a, b)
+	if !ok {
+		return reflect.DeepEqual(a, b)
+	}
+	return c == 0
+}
+
+func neHelper(a any, b any) bool {
+	return !eqHelper(a, b)
+}
+
+func ltHelper(a any, b any) bool {
+	c, ok := compare(a, b)
+	return ok && c < 0
+}
+
+func lteHelper(a any, b any) bool {
+	c, ok := compare(a, b)
+	return ok && c <= 0
+}
+
+func gtHelper(a any, b any) bool {
+	c, ok := compare(a, b)
+	return ok && c > 0
+}
+
+func gteHelper(a any, b any) bool {
+	c, ok := compare(a, b)
+	return ok && c >= 0
+}
+
+func andHelper(a any, b any) bool {
+	return raymond.IsTrue(a) && raymond.IsTrue(b)
+}
+
+func orHelper(a any, b any) bool {
+	return raymond.IsTrue(a) || raymond.IsTrue(b)
+}
+
+func notHelper(a any) bool {
+	return !raymond.IsTrue(a)
+}
+
+// compare orders two numbers, strings or times. Numbers of different types are compared by value.
+func compare(a any, b any) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(value any) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// assetHelper resolves the fingerprinted URL of a CSS or JS file of the theme being rendered. Theme assets have no
+// names, so a file is chosen by its kind and its position among the files of that kind, which starts at 0. A
+// fingerprinted file name resolves to itself. An asset that the theme does not have resolves to an empty string.
+//
+//	{{ asset "css" }}        => /_assets/1f2e3d4c5b6a7980.css
+//	{{ asset "js" index=1 }} => the URL of the second JS file
+func assetHelper(assets *compiledAssets) func(name string, options *raymond.Options) string {
+	return func(name string, options *raymond.Options) string {
+		var names []string
+		switch name {
+		case "css":
+			names = assets.css
+		case "js":
+			names = assets.js
+		default:
+			name = strings.TrimPrefix(name, "/")
+			if _, ok := assets.files[name]; ok {
+				return pathAssets + "/" + name
+			}
+			return ""
+		}
+		index, _ := options.HashProp("index").(int)
+		if index < 0 || index >= len(names) {
+			return ""
+		}
+		return pathAssets + "/" + names[index]
+	}
+}
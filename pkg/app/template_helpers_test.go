@@ -0,0 +1,119 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func renderHelper(t *testing.T, source string, data any) string {
+	t.Helper()
+	c, err := compileTheme(ulid.Make(), map[string]string{"default": source}, nil)
+	require.NoError(t, err)
+	return execTheme(t, c, "default", data)
+}
+
+func TestFormatDateHelper(t *testing.T) {
+	published := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+	data := map[string]any{"published": published, "text": "2024-03-05T14:30:00Z", "zero": time.Time{}}
+	assert.Equal(t, "March 5, 2024", renderHelper(t, `{{formatDate published}}`, data))
+	assert.Equal(t, "05/03/2024", renderHelper(t, `{{formatDate published format="02/01/2006"}}`, data))
+	assert.Equal(t, "2024-03-05", renderHelper(t, `{{formatDate text format="date"}}`, data))
+	assert.Equal(t, "2024-03-05 08:30:00", renderHelper(t, `{{formatDate published format="datetime" tz="America/Chicago"}}`, data))
+	assert.Equal(t, "", renderHelper(t, `{{formatDate zero}}`, data))
+}
+
+func TestURLHelper(t *testing.T) {
+	data := map[string]any{"slug": "hello world", "page": 2}
+	assert.Equal(t, "/about", renderHelper(t, `{{url "/about"}}`, data))
+	assert.Equal(t, "/blog/hello%20world", renderHelper(t, `{{url "/blog/:slug" slug=slug}}`, data))
+	assert.Equal(t, "/blog/hello%20world?page=2", renderHelper(t, `{{url "/blog/:slug" slug=slug page=page}}`, data))
+	assert.Equal(t, "/docs/guide/install", renderHelper(t, `{{url "/docs/*rest" rest="guide/install"}}`, data))
+	assert.Equal(t, "/docs/intro", renderHelper(t, `{{url "/docs/*" wildcard="intro"}}`, data))
+}
+
+func TestAssetHelper(t *testing.T) {
+	assets := compileAssets([][]byte{[]byte("a{}"), []byte("b{}")}, [][]byte{[]byte("x()")}, core.ThemeAssetOptions{})
+	render := func(source string) string {
+		c, err := compileTheme(ulid.Make(), map[string]string{"default": source}, assets)
+		require.NoError(t, err)
+		return execTheme(t, c, "default", nil)
+	}
+	assert.Equal(t, "/_assets/"+assets.css[0], render(`{{asset "css"}}`))
+	assert.Equal(t, "/_assets/"+assets.css[1], render(`{{asset "css" index=1}}`))
+	assert.Equal(t, "/_assets/"+assets.js[0], render(`{{asset "js"}}`))
+	assert.Equal(t, "/_assets/"+assets.js[0], render(`{{asset "/`+assets.js[0]+`"}}`))
+	assert.Equal(t, "", render(`{{asset "css" index=2}}`))
+	assert.Equal(t, "", render(`{{asset "logo.svg"}}`))
+	assert.Equal(t, "", renderHelper(t, `{{asset "css"}}`, nil), "a theme without assets resolves nothing")
+}
+
+func TestTruncateHelper(t *testing.T) {
+	data := map[string]any{"text": "The quick brown fox jumps"}
+	assert.Equal(t, "The quick brown fox jumps", renderHelper(t, `{{truncate text 100}}`, data))
+	assert.Equal(t, "The quick…", renderHelper(t, `{{truncate text 12}}`, data))
+	assert.Equal(t, "The quick...", renderHelper(t, `{{truncate text 14 suffix="..."}}`, data))
+	assert.Equal(t, "Thequi…", renderHelper(t, `{{truncate "Thequickbrown" 7}}`, data))
+}
+
+func TestSlugifyHelper(t *testing.T) {
+	assert.Equal(t, "hello-world", renderHelper(t, `{{slugify "Hello, World!"}}`, nil))
+	assert.Equal(t, "crème-brûlée-2024", renderHelper(t, `{{slugify "  Crème Brûlée -- 2024 "}}`, nil))
+}
+
+func TestJSONHelper(t *testing.T) {
+	data := map[string]any{"tags": []string{"a", "<b>"}}
+	assert.Equal(t, `["a","\u003cb\u003e"]`, renderHelper(t, `{{json tags}}`, data))
+}
+
+func TestJoinHelper(t *testing.T) {
+	data := map[string]any{"tags": []string{"go", "web"}, "nums": []int{1, 2}}
+	assert.Equal(t, "go, web", renderHelper(t, `{{join tags ", "}}`, data))
+	assert.Equal(t, "1-2", renderHelper(t, `{{join nums "-"}}`, data))
+}
+
+func TestComparisonHelpers(t *testing.T) {
+	data := map[string]any{
+		"n":     3,
+		"f":     3.0,
+		"s":     "b",
+		"early": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		"late":  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	testCases := map[string]string{
+		`{{eq n f}}`:               "true",
+		`{{eq s "b"}}`:             "true",
+		`{{ne s "a"}}`:             "true",
+		`{{lt n 4}}`:               "true",
+		`{{lte n 3}}`:              "true",
+		`{{gt s "a"}}`:             "true",
+		`{{gte n 4}}`:              "false",
+		`{{lt early late}}`:        "true",
+		`{{lt s n}}`:               "false",
+		`{{and n s}}`:              "true",
+		`{{or missing ""}}`:        "false",
+		`{{not missing}}`:          "true",
+		`{{#if (gt n 2)}}y{{/if}}`: "y",
+	}
+	for source, expected := range testCases {
+		assert.Equal(t, expected, renderHelper(t, source, data), source)
+	}
+}
+
+func TestRegisterHelper(t *testing.T) {
+	RegisterHelper("shout", func(s string) string {
+		return s + "!"
+	})
+	t.Cleanup(func() {
+		delete(templateHelpers.helpers, "shout")
+	})
+	assert.Equal(t, "hi!", renderHelper(t, `{{shout "hi"}}`, nil))
+	assert.Panics(t, func() { RegisterHelper("shout", func() string { return "" }) })
+	assert.Panics(t, func() { RegisterHelper("eq", func() string { return "" }) })
+	assert.Panics(t, func() { RegisterHelper("extend", func() string { return "" }) })
+	assert.Panics(t, func() { RegisterHelper("bad", "not a func") })
+}
@@ -2,73 +2,229 @@ package app
 
 import (
 	"context"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/aarongodin/pagebin/pkg/core"
 	"github.com/aarongodin/pagebin/pkg/store"
 	"github.com/aymerick/raymond"
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/oklog/ulid/v2"
 )
 
+const (
+	partialPrefix = "partials/"
+	// otherThemesCacheSize is the number of themes besides the current theme that are kept compiled.
+	otherThemesCacheSize = 8
+)
+
 // ThemeManager controls how a theme is used during rendering
 type ThemeManager interface {
-	Render(templateName string, data any) ([]byte, error)
+	// Render executes a template of a theme. The current theme is always compiled, while other themes, such as the
+	// theme of a draft being previewed, are compiled on first use and kept in a small cache.
+	Render(ctx context.Context, theme ulid.ULID, templateName string, data any) ([]byte, error)
+	// RenderBlocks renders a block document with the block templates of a theme.
+	RenderBlocks(ctx context.Context, theme ulid.ULID, doc core.BlockDocument) ([]byte, error)
+	// Asset returns the body and content type of a fingerprinted CSS or JS file of the current theme or a cached theme.
+	Asset(name string) ([]byte, string, error)
+	// Assets returns the names of the fingerprinted CSS and JS files of a theme.
+	Assets(ctx context.Context, theme ulid.ULID) ([]string, error)
 	Load(ctx context.Context, uid ulid.ULID) error
 }
 
 type themeManager struct {
+	mu      sync.RWMutex
 	current *compiledTheme
+	others  *lru.Cache[ulid.ULID, *compiledTheme]
 	themes  store.ThemeStore
 	blob    store.BlobStore
 }
 
-func (m themeManager) Render(templateName string, data any) ([]byte, error) {
-	if m.current == nil {
-		return nil, core.ErrThemeNotCompiled.NewWithNoMessage()
+func (m *themeManager) Render(ctx context.Context, theme ulid.ULID, templateName string, data any) ([]byte, error) {
+	c, err := m.theme(ctx, theme)
+	if err != nil {
+		return nil, err
 	}
-	tpl, ok := m.current.templates[templateName]
+	tpl, ok := c.templates[templateName]
 	if !ok {
-		return nil, core.ErrThemeTemplateNotFound.New("template \"%s\" not found for current theme", templateName)
+		return nil, core.ErrThemeTemplateNotFound.New("template \"%s\" not found for theme %s", templateName, theme.String())
+	}
+	frame := raymond.NewDataFrame()
+	if rc, ok := data.(RenderContext); ok && rc.pages != nil {
+		frame.Set(dataPageQuerier, rc.pages)
 	}
-	out, err := tpl.Exec(data)
+	out, err := tpl.ExecWith(data, frame)
 	if err != nil {
 		return nil, core.ErrThemeTemplateExec.Wrap(err, "template \"%s\" failed to execute", templateName)
 	}
 	return []byte(out), nil
 }
 
+func (m *themeManager) RenderBlocks(ctx context.Context, theme ulid.ULID, doc core.BlockDocument) ([]byte, error) {
+	c, err := m.theme(ctx, theme)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.renderBlocks(doc.Blocks)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func (m *themeManager) Asset(name string) ([]byte, string, error) {
+	m.mu.RLock()
+	current := m.current
+	m.mu.RUnlock()
+	if current == nil {
+		return nil, "", core.ErrThemeNotCompiled.NewWithNoMessage()
+	}
+	if asset, ok := current.assets.files[name]; ok {
+		return asset.body, asset.contentType, nil
+	}
+	for _, c := range m.others.Values() {
+		if asset, ok := c.assets.files[name]; ok {
+			return asset.body, asset.contentType, nil
+		}
+	}
+	return nil, "", core.ErrThemeAssetNotFound.New("asset \"%s\" not found", name)
+}
+
+func (m *themeManager) Assets(ctx context.Context, theme ulid.ULID) ([]string, error) {
+	c, err := m.theme(ctx, theme)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(c.assets.files))
+	for name := range c.assets.files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
 func (m *themeManager) Load(ctx context.Context, uid ulid.ULID) error {
-	c := &compiledTheme{
-		uid:       uid,
-		templates: map[string]*raymond.Template{},
+	c, err := m.compile(ctx, uid)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.current = c
+	m.mu.Unlock()
+	return nil
+}
+
+// theme returns the current theme when uid matches it, or compiles any other theme on demand.
+func (m *themeManager) theme(ctx context.Context, uid ulid.ULID) (*compiledTheme, error) {
+	m.mu.RLock()
+	current := m.current
+	m.mu.RUnlock()
+	if current == nil {
+		return nil, core.ErrThemeNotCompiled.NewWithNoMessage()
+	}
+	if current.uid == uid {
+		return current, nil
+	}
+	if c, ok := m.others.Get(uid); ok {
+		return c, nil
+	}
+	c, err := m.compile(ctx, uid)
+	if err != nil {
+		return nil, err
 	}
+	m.others.Add(uid, c)
+	return c, nil
+}
+
+func (m *themeManager) compile(ctx context.Context, uid ulid.ULID) (*compiledTheme, error) {
 	theme, err := m.themes.GetTheme(ctx, uid)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	sources := make(map[string]string, len(theme.Templates))
 	for templateName, templateUID := range theme.Templates {
 		tplBlob, err := m.blob.GetBytes(ctx, templateUID)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		tpl, err := raymond.Parse(string(tplBlob))
+		sources[templateName] = string(tplBlob)
+	}
+	css, err := m.assetBlobs(ctx, theme.CSSAssets)
+	if err != nil {
+		return nil, err
+	}
+	js, err := m.assetBlobs(ctx, theme.JSAssets)
+	if err != nil {
+		return nil, err
+	}
+	return compileTheme(uid, sources, compileAssets(css, js, theme.AssetOptions))
+}
+
+func (m *themeManager) assetBlobs(ctx context.Context, uids []ulid.ULID) ([][]byte, error) {
+	blobs := make([][]byte, len(uids))
+	for i, uid := range uids {
+		b, err := m.blob.GetBytes(ctx, uid)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		c.templates[templateName] = tpl
+		blobs[i] = b
 	}
-	// TODO: CSS and JS asset caching
-	m.current = c
-	return nil
+	return blobs, nil
 }
 
+// compiledTheme holds the parsed templates of a theme. Partials and helpers are registered on each template rather than
+// globally with raymond, so that nothing leaks between themes.
 type compiledTheme struct {
 	uid       ulid.ULID
 	templates map[string]*raymond.Template
+	partials  map[string]*raymond.Template
+	assets    *compiledAssets
 }
 
-func NewThemeManager(themeStore store.ThemeStore, blobStore store.BlobStore) ThemeManager {
+// compileTheme parses the template sources of a theme. Templates named with the partials/ prefix are registered as
+// partials of every other template under the name without the prefix, so partials/header is used as {{> header}}.
+// Asset markers are replaced with the tags of the compiled assets before parsing.
+func compileTheme(uid ulid.ULID, sources map[string]string, assets *compiledAssets) (*compiledTheme, error) {
+	if assets == nil {
+		assets = compileAssets(nil, nil, core.ThemeAssetOptions{})
+	}
+	c := &compiledTheme{
+		uid:       uid,
+		templates: map[string]*raymond.Template{},
+		partials:  map[string]*raymond.Template{},
+		assets:    assets,
+	}
+	for templateName, source := range sources {
+		tpl, err := raymond.Parse(assets.replaceMarkers(source))
+		if err != nil {
+			return nil, err
+		}
+		if partialName, ok := strings.CutPrefix(templateName, partialPrefix); ok {
+			c.partials[partialName] = tpl
+		} else {
+			c.templates[templateName] = tpl
+		}
+	}
+	for _, tpl := range c.templates {
+		for partialName, partial := range c.partials {
+			tpl.RegisterPartialTemplate(partialName, partial)
+		}
+		c.registerLayoutHelpers(tpl)
+		tpl.RegisterHelper("asset", assetHelper(c.assets))
+		templateHelpers.install(tpl)
+	}
+	return c, nil
+}
+
+func NewThemeManager(themeStore store.ThemeStore, blobStore store.BlobStore) (ThemeManager, error) {
+	others, err := lru.New[ulid.ULID, *compiledTheme](otherThemesCacheSize)
+	if err != nil {
+		return nil, err
+	}
 	return &themeManager{
+		others: others,
 		themes: themeStore,
 		blob:   blobStore,
-	}
+	}, nil
 }
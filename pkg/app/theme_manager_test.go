@@ -0,0 +1,57 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func execTheme(t *testing.T, c *compiledTheme, templateName string, data any) string {
+	t.Helper()
+	tpl, ok := c.templates[templateName]
+	require.True(t, ok)
+	out, err := tpl.Exec(data)
+	require.NoError(t, err)
+	return out
+}
+
+func TestCompileThemePartials(t *testing.T) {
+	first, err := compileTheme(ulid.Make(), map[string]string{
+		"default":         "{{> header}}<main>{{ title }}</main>",
+		"partials/header": "<header>{{> nav}}</header>",
+		"partials/nav":    "<nav>{{ title }}</nav>",
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "<header><nav>Home</nav></header><main>Home</main>", execTheme(t, first, "default", map[string]string{"title": "Home"}))
+	assert.NotContains(t, first.templates, "partials/header", "partials are not rendered as templates")
+
+	second, err := compileTheme(ulid.Make(), map[string]string{
+		"default": "{{> header}}",
+	}, nil)
+	require.NoError(t, err)
+	_, err = second.templates["default"].Exec(nil)
+	assert.Error(t, err, "partials of another theme are not visible")
+}
+
+func TestCompileThemeLayouts(t *testing.T) {
+	c, err := compileTheme(ulid.Make(), map[string]string{
+		"base":    "<title>{{#block \"title\"}}Site{{/block}}</title><body>{{#block \"main\"}}{{/block}}</body>",
+		"section": "{{#extend \"base\"}}{{#fill \"title\"}}Section{{/fill}}{{#fill \"main\"}}<div>{{#block \"main\"}}{{/block}}</div>{{/fill}}{{/extend}}",
+		"page":    "{{#extend \"base\"}}{{#fill \"main\"}}<p>{{ title }}</p>{{/fill}}{{/extend}}",
+		"nested":  "{{#extend \"section\"}}{{#fill \"title\"}}Nested{{/fill}}ignored{{/extend}}",
+		"loop":    "{{#extend \"loop\"}}{{/extend}}",
+		"missing": "{{#extend \"unknown\"}}{{/extend}}",
+	}, nil)
+	require.NoError(t, err)
+	data := map[string]string{"title": "Hello"}
+
+	assert.Equal(t, "<title>Site</title><body><p>Hello</p></body>", execTheme(t, c, "page", data))
+	assert.Equal(t, "<title>Nested</title><body><div></div></body>", execTheme(t, c, "nested", data))
+
+	_, err = c.templates["loop"].Exec(data)
+	assert.Error(t, err)
+	_, err = c.templates["missing"].Exec(data)
+	assert.Error(t, err)
+}
@@ -17,3 +17,33 @@ func getUIDParam(ctx *fiber.Ctx, name string) (ulid.ULID, error) {
 	}
 	return parsed, nil
 }
+
+// getVersionParam parses a version UID route param, which may also be "current" or "next" to refer to the versions of
+// the site.
+func getVersionParam(ctx *fiber.Ctx, svc Service, name string) (ulid.ULID, error) {
+	switch ctx.Params(name) {
+	case "current", "next":
+		site, err := svc.GetSite(ctx.Context())
+		if err != nil {
+			return ulid.ULID{}, err
+		}
+		if ctx.Params(name) == "next" {
+			return site.NextVersion, nil
+		}
+		return site.Version, nil
+	default:
+		return getUIDParam(ctx, name)
+	}
+}
+
+func getCursorQuery(ctx *fiber.Ctx) (*ulid.ULID, error) {
+	raw := ctx.Query("cursor")
+	if raw == "" {
+		return nil, nil
+	}
+	parsed, err := ulid.Parse(raw)
+	if err != nil {
+		return nil, core.ErrUIDRequired.Wrap(err, "cursor must be a UID")
+	}
+	return &parsed, nil
+}
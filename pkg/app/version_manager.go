@@ -2,63 +2,113 @@ package app
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 
 	"github.com/aarongodin/pagebin/pkg/core"
 	"github.com/aarongodin/pagebin/pkg/store"
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/oklog/ulid/v2"
 )
 
+// otherVersionsCacheSize is the number of versions besides the current and next versions that are kept compiled.
+const otherVersionsCacheSize = 8
+
 type VersionManager interface {
-	GetByPath(ctx context.Context, targetVersion *core.TargetVersion, path string) (ulid.ULID, error)
+	// GetByPath returns the page for a request path and appends the params captured by its route to params, which
+	// may come from acquireParams so that the lookup does not allocate.
+	GetByPath(ctx context.Context, targetVersion *core.TargetVersion, path string, params *Params) (ulid.ULID, error)
+	GetRedirect(ctx context.Context, targetVersion *core.TargetVersion, path string) (string, int, bool, error)
 	Load(ctx context.Context, currentUID ulid.ULID, nextUID ulid.ULID) error
 	SetPage(previousPath string, path string, pageUID ulid.ULID) error
 	UnsetPage(path string) error
+	SetRedirects(redirects []core.Redirect) error
+	// PurgeVersion drops a version other than the current and next versions from the cache, so that it is compiled
+	// again with its changes on next use.
+	PurgeVersion(uid ulid.ULID)
 }
 
 type versionManager struct {
-	current  *compiledVersion
-	next     *compiledVersion
+	mu      sync.RWMutex
+	current *compiledVersion
+	next    *compiledVersion
+	others  *lru.Cache[ulid.ULID, *compiledVersion]
+	// purges counts calls to PurgeVersion, so that a version compiled while it was purged is not cached.
+	purges   atomic.Uint64
 	versions store.VersionStore
 }
 
 // This could probably be placed in the core
 type compiledVersion struct {
-	uid   ulid.ULID
-	index map[string]ulid.ULID // TODO: this is where I'd put an optimized index like a radix tree..... IF I HAD ONE
+	uid       ulid.ULID
+	index     *router
+	redirects *compiledRedirects
+}
+
+// Find returns the page for a request path and appends the params captured by the matching route to params.
+func (c compiledVersion) Find(value string, params *Params) (ulid.ULID, bool) {
+	return c.index.lookup(value, params)
+}
+
+func (m *versionManager) GetByPath(ctx context.Context, targetVersion *core.TargetVersion, path string, params *Params) (ulid.ULID, error) {
+	targetCompiledVersion, err := m.target(ctx, targetVersion)
+	if err != nil {
+		return ulid.ULID{}, err
+	}
+	// the next version is edited in place
+	m.mu.RLock()
+	uid, exists := targetCompiledVersion.Find(path, params)
+	m.mu.RUnlock()
+	if !exists {
+		return ulid.ULID{}, core.ErrPageNotFound.NewWithNoMessage()
+	}
+	return uid, nil
 }
 
-func (c compiledVersion) Find(value string) (ulid.ULID, bool) {
-	uid, exists := c.index[value]
-	return uid, exists
+// GetRedirect returns the location and status code when the path matches a redirect of the target version.
+func (m *versionManager) GetRedirect(ctx context.Context, targetVersion *core.TargetVersion, path string) (string, int, bool, error) {
+	targetCompiledVersion, err := m.target(ctx, targetVersion)
+	if err != nil {
+		return "", 0, false, err
+	}
+	m.mu.RLock()
+	location, status, ok := targetCompiledVersion.redirects.match(path)
+	m.mu.RUnlock()
+	return location, status, ok, nil
 }
 
-func (m *versionManager) GetByPath(ctx context.Context, targetVersion *core.TargetVersion, path string) (ulid.ULID, error) {
+// target returns the compiled current or next version. Any other version is compiled on first use, without holding
+// the lock, and kept in a small cache until it is purged.
+func (m *versionManager) target(ctx context.Context, targetVersion *core.TargetVersion) (*compiledVersion, error) {
 	var targetCompiledVersion *compiledVersion
 	switch {
 	case targetVersion.IsCurrent():
+		m.mu.RLock()
 		targetCompiledVersion = m.current
+		m.mu.RUnlock()
 	case targetVersion.IsNext():
+		m.mu.RLock()
 		targetCompiledVersion = m.next
+		m.mu.RUnlock()
 	default:
-		version, err := m.versions.GetVersion(ctx, targetVersion.UID())
+		uid := targetVersion.UID()
+		if compiled, ok := m.others.Get(uid); ok {
+			return compiled, nil
+		}
+		purges := m.purges.Load()
+		compiled, err := m.load(ctx, uid)
 		if err != nil {
-			return ulid.ULID{}, err
+			return nil, err
 		}
-		pageUID, exists := version.Pages[path]
-		if !exists {
-			return ulid.ULID{}, core.ErrPageNotFound.NewWithNoMessage()
+		if m.purges.Load() == purges {
+			m.others.Add(uid, compiled)
 		}
-		return pageUID, nil
+		targetCompiledVersion = compiled
 	}
-
 	if targetCompiledVersion == nil || targetCompiledVersion.index == nil {
-		return ulid.ULID{}, core.ErrVersionNotCompiled.New("version not compiled")
-	}
-	uid, exists := targetCompiledVersion.Find(path)
-	if !exists {
-		return ulid.ULID{}, core.ErrPageNotFound.NewWithNoMessage()
+		return nil, core.ErrVersionNotCompiled.New("version not compiled")
 	}
-	return uid, nil
+	return targetCompiledVersion, nil
 }
 
 func (m *versionManager) Load(ctx context.Context, currentUID ulid.ULID, nextUID ulid.ULID) error {
@@ -70,8 +120,10 @@ func (m *versionManager) Load(ctx context.Context, currentUID ulid.ULID, nextUID
 	if err != nil {
 		return err
 	}
+	m.mu.Lock()
 	m.current = current
 	m.next = next
+	m.mu.Unlock()
 	return nil
 }
 
@@ -80,33 +132,64 @@ func (m *versionManager) load(ctx context.Context, uid ulid.ULID) (*compiledVers
 	if err != nil {
 		return nil, err
 	}
+	redirects, err := compileRedirects(version.Redirects)
+	if err != nil {
+		return nil, err
+	}
 	return &compiledVersion{
-		uid:   uid,
-		index: version.Pages,
+		uid:       uid,
+		index:     newRouter(version.Pages),
+		redirects: redirects,
 	}, nil
 }
 
 func (m *versionManager) SetPage(previousPath string, path string, pageUID ulid.ULID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.next == nil || m.next.index == nil {
 		return core.ErrVersionNotCompiled.New("next version not compiled")
 	}
 	if previousPath != "" {
-		delete(m.next.index, previousPath)
+		m.next.index.remove(previousPath)
 	}
-	m.next.index[path] = pageUID
+	m.next.index.insert(path, pageUID)
 	return nil
 }
 
 func (m *versionManager) UnsetPage(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.next == nil || m.next.index == nil {
 		return core.ErrVersionNotCompiled.New("next version not compiled")
 	}
-	delete(m.next.index, path)
+	m.next.index.remove(path)
+	return nil
+}
+
+func (m *versionManager) SetRedirects(redirects []core.Redirect) error {
+	compiled, err := compileRedirects(redirects)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.next == nil {
+		return core.ErrVersionNotCompiled.New("next version not compiled")
+	}
+	m.next.redirects = compiled
 	return nil
 }
 
+func (m *versionManager) PurgeVersion(uid ulid.ULID) {
+	m.purges.Add(1)
+	m.others.Remove(uid)
+}
+
 func NewVersionManager(versions store.VersionStore) VersionManager {
+	// the size is a constant, which is the only error lru.New returns
+	others, _ := lru.New[ulid.ULID, *compiledVersion](otherVersionsCacheSize)
 	return &versionManager{
+		others:   others,
 		versions: versions,
 	}
 }
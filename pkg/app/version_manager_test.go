@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBenchmarkVersionManager(pages map[string]ulid.ULID) (*versionManager, *core.TargetVersion) {
+	uid := ulid.Make()
+	m := NewVersionManager(nil).(*versionManager)
+	m.current = &compiledVersion{uid: uid, index: newRouter(pages)}
+	return m, core.NewCurrentTargetVersion(uid)
+}
+
+func TestVersionManagerGetByPathDoesNotAllocate(t *testing.T) {
+	pages := benchmarkPages(100)
+	pages["/blog/:slug"] = ulid.Make()
+	pages["/docs/*rest"] = ulid.Make()
+	m, target := newBenchmarkVersionManager(pages)
+	ctx := context.Background()
+
+	for _, path := range []string{"/section-7/page-7", "/blog/hello-world", "/docs/a/b/c"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			params := acquireParams()
+			if _, err := m.GetByPath(ctx, target, path, params); err != nil {
+				t.Fatal(err)
+			}
+			releaseParams(params)
+		})
+		assert.Zero(t, allocs, path)
+	}
+
+	params := acquireParams()
+	defer releaseParams(params)
+	uid, err := m.GetByPath(ctx, target, "/blog/hello-world", params)
+	require.NoError(t, err)
+	assert.Equal(t, pages["/blog/:slug"], uid)
+	assert.Equal(t, map[string]string{"slug": "hello-world"}, params.Map())
+}
+
+func TestVersionManagerOtherVersions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	m := s.VersionManager().(*versionManager)
+	draft := createTestDraft(t, s, "feature")
+
+	params := acquireParams()
+	defer releaseParams(params)
+	_, err := m.GetByPath(ctx, draft, "/", params)
+	require.NoError(t, err)
+	compiled, ok := m.others.Get(draft.UID())
+	require.True(t, ok, "other versions are cached once compiled")
+	_, err = m.GetByPath(ctx, draft, "/", params)
+	require.NoError(t, err)
+	cached, _ := m.others.Get(draft.UID())
+	assert.Same(t, compiled, cached)
+
+	page := putTestPage(t, s, draft, nil, "/draft", "# Draft")
+	assert.False(t, m.others.Contains(draft.UID()), "writes purge the version")
+	uid, err := m.GetByPath(ctx, draft, "/draft", params)
+	require.NoError(t, err)
+	assert.Equal(t, page.UID, uid)
+}
+
+func BenchmarkVersionManagerStatic(b *testing.B) {
+	m, target := newBenchmarkVersionManager(benchmarkPages(1000))
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params := acquireParams()
+		m.GetByPath(ctx, target, "/section-7/page-507", params)
+		releaseParams(params)
+	}
+}
+
+func BenchmarkVersionManagerParam(b *testing.B) {
+	pages := benchmarkPages(1000)
+	pages["/blog/:slug"] = ulid.Make()
+	m, target := newBenchmarkVersionManager(pages)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params := acquireParams()
+		m.GetByPath(ctx, target, "/blog/hello-world", params)
+		releaseParams(params)
+	}
+}
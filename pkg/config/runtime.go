@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 )
 
 // RuntimeConfig is the set of configurable options that are read when the program starts.
@@ -14,8 +15,30 @@ type RuntimeConfig struct {
 	BlobBackend        string `env:"BLOB_BACKEND" envDefault:"localfs"`
 	BlobLocalFSRootDir string `env:"BLOB_LOCAL_FS_ROOT_DIR" envDefault:"pagebin-content"`
 	ContentCacheSize   int    `env:"CONTENT_CACHE_SIZE" envDefault:"100"`
+	// RenderCacheMaxBytes is the memory budget for rendered page output. Zero disables the render cache.
+	RenderCacheMaxBytes int64 `env:"RENDER_CACHE_MAX_BYTES" envDefault:"67108864"`
+	// Cache-Control headers for pages of the current version, for pages of any other version, and for theme assets.
+	CacheControlPages    string `env:"CACHE_CONTROL_PAGES" envDefault:"public, max-age=0, must-revalidate"`
+	CacheControlPreviews string `env:"CACHE_CONTROL_PREVIEWS" envDefault:"private, no-cache"`
+	CacheControlAssets   string `env:"CACHE_CONTROL_ASSETS" envDefault:"public, max-age=31536000, immutable"`
+	// SchedulerMissedPolicy controls schedules whose run time passed while the server was down.
+	// "run" publishes them on startup and "skip" marks them as skipped. Other values fail startup.
+	SchedulerMissedPolicy string `env:"SCHEDULER_MISSED_POLICY" envDefault:"run"`
+	// Garbage collection keeps the current, next and draft versions, plus any published version that is either one of
+	// the most recent GCRetainVersions or was published within GCRetainAge. Unpublished versions, such as those of
+	// deleted drafts, are kept the same way by the time they were created.
+	GCRetainVersions int           `env:"GC_RETAIN_VERSIONS" envDefault:"10"`
+	GCRetainAge      time.Duration `env:"GC_RETAIN_AGE" envDefault:"720h"`
+	// PreviewSecret signs preview tokens. When empty, a random secret is used and tokens do not survive a restart.
+	PreviewSecret     string        `env:"PREVIEW_SECRET"`
+	PreviewDefaultTTL time.Duration `env:"PREVIEW_DEFAULT_TTL" envDefault:"168h"`
 }
 
+const (
+	SchedulerMissedPolicyRun  = "run"
+	SchedulerMissedPolicySkip = "skip"
+)
+
 // ServerAddr returns the concatenated hostname with port.
 func (cfg RuntimeConfig) ServerAddr() string {
 	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
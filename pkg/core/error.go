@@ -6,23 +6,53 @@ import (
 
 var (
 	traitUnexpected = errorx.RegisterTrait("unexpected")
+	traitInvalid    = errorx.RegisterTrait("invalid")
+	traitConflict   = errorx.RegisterTrait("conflict")
 
 	errApp                   = errorx.NewNamespace("app")
 	ErrUnknown               = errorx.NewType(errApp, "unknown", traitUnexpected)
 	ErrUnknownBlobBackend    = errorx.NewType(errApp, "unknown_blob_backend", traitUnexpected)
+	ErrUnknownMissedPolicy   = errorx.NewType(errApp, "unknown_missed_policy", traitUnexpected)
 	ErrPageNotFound          = errorx.NewType(errApp, "page_not_found", errorx.NotFound())
 	ErrThemeNotCompiled      = errorx.NewType(errApp, "theme_not_compiled", traitUnexpected)
 	ErrThemeTemplateNotFound = errorx.NewType(errApp, "theme_template_not_found")
 	ErrThemeTemplateExec     = errorx.NewType(errApp, "theme_template_exec")
+	ErrThemeAssetNotFound    = errorx.NewType(errApp, "theme_asset_not_found", errorx.NotFound())
 	ErrVersionNotCompiled    = errorx.NewType(errApp, "version_not_compiled", traitUnexpected)
-	ErrReservedPath          = errorx.NewType(errApp, "reserved_path")
-	ErrInvalidVersion        = errorx.NewType(errApp, "invalid_version")
-	ErrUIDRequired           = errorx.NewType(errApp, "uid_required")
+	ErrReservedPath          = errorx.NewType(errApp, "reserved_path", errorx.NotFound())
+	ErrInvalidPath           = errorx.NewType(errApp, "invalid_path", traitInvalid)
+	ErrInvalidContent        = errorx.NewType(errApp, "invalid_content", traitInvalid)
+	ErrInvalidVersion        = errorx.NewType(errApp, "invalid_version", traitInvalid)
+	ErrVersionIncomplete     = errorx.NewType(errApp, "version_incomplete", traitConflict)
+	ErrUIDRequired           = errorx.NewType(errApp, "uid_required", traitInvalid)
+	ErrInvalidSchedule       = errorx.NewType(errApp, "invalid_schedule", traitInvalid)
+	ErrInvalidPreview        = errorx.NewType(errApp, "invalid_preview", traitInvalid)
+	ErrInvalidDraft          = errorx.NewType(errApp, "invalid_draft", traitInvalid)
+	ErrDraftNotFound         = errorx.NewType(errApp, "draft_not_found", errorx.NotFound())
+	ErrMergeConflict         = errorx.NewType(errApp, "merge_conflict", traitConflict)
+	ErrInvalidRedirect       = errorx.NewType(errApp, "invalid_redirect", traitInvalid)
+	ErrRedirectNotFound      = errorx.NewType(errApp, "redirect_not_found", errorx.NotFound())
+	ErrInvalidPageQuery      = errorx.NewType(errApp, "invalid_page_query", traitInvalid)
+	ErrInvalidFeed           = errorx.NewType(errApp, "invalid_feed", traitInvalid)
+	ErrInvalidSite           = errorx.NewType(errApp, "invalid_site", traitInvalid)
+	ErrInvalidSearch         = errorx.NewType(errApp, "invalid_search", traitInvalid)
+	ErrInvalidFrontMatter    = errorx.NewType(errApp, "invalid_front_matter", traitInvalid)
 
 	errStore                = errorx.NewNamespace("store")
 	ErrItemNotFound         = errorx.NewType(errStore, "item_not_found", errorx.NotFound())
 	ErrBucketNotFound       = errorx.NewType(errStore, "bucket_not_found", errorx.NotFound())
+	ErrSearchIndexNotFound  = errorx.NewType(errStore, "search_index_not_found", errorx.NotFound())
 	ErrTransactionNotFound  = errorx.NewType(errStore, "tx_not_found", traitUnexpected)
 	ErrTransactionEnd       = errorx.NewType(errStore, "tx_end", traitUnexpected)
 	ErrTransactionPrivilege = errorx.NewType(errStore, "tx_privilege", traitUnexpected)
 )
+
+// IsInvalid reports whether an error was caused by invalid input.
+func IsInvalid(err error) bool {
+	return errorx.HasTrait(err, traitInvalid)
+}
+
+// IsConflict reports whether an error was caused by a conflict with the current state.
+func IsConflict(err error) bool {
+	return errorx.HasTrait(err, traitConflict)
+}
@@ -3,21 +3,93 @@ package core
 import (
 	"crypto/sha256"
 	"slices"
+	"time"
 
 	"github.com/oklog/ulid/v2"
 )
 
 type Site struct {
-	UID         ulid.ULID `json:"uid"`
-	Title       string    `json:"title"`
-	Version     ulid.ULID `json:"version"`
-	NextVersion ulid.ULID `json:"nextVersion"`
+	UID         ulid.ULID            `json:"uid"`
+	Title       string               `json:"title"`
+	Version     ulid.ULID            `json:"version"`
+	NextVersion ulid.ULID            `json:"nextVersion"`
+	Drafts      map[string]ulid.ULID `json:"drafts"`
+	// UpdatedAt is the last time the settings or the current version of the site changed.
+	UpdatedAt time.Time `json:"updatedAt"`
+	// URL is the public URL of the site, such as https://example.com, used for absolute links. When empty, links are
+	// made absolute with the URL of the request.
+	URL string `json:"url"`
+	// Feeds are the feeds served by the site. A nil list serves DefaultFeeds, while an empty list serves none.
+	Feeds []Feed `json:"feeds"`
+	// Robots is the body of /robots.txt. When empty, every path is allowed and the sitemap is listed.
+	Robots string `json:"robots"`
+}
+
+// SiteUpdate changes the settings of a site. Nil fields, and an empty title, are left unchanged.
+type SiteUpdate struct {
+	Title  *string `json:"title"`
+	URL    *string `json:"url"`
+	Feeds  *[]Feed `json:"feeds"`
+	Robots *string `json:"robots"`
+}
+
+const (
+	FeedFormatRSS  = "rss"
+	FeedFormatAtom = "atom"
+	FeedFormatJSON = "json"
+)
+
+// Feed is a feed of the most recent pages of a version, optionally filtered by tag or path prefix. A path with a :tag
+// segment, such as /tags/:tag/feed.xml, serves a feed for every tag. The title defaults to the site title, and the
+// limit defaults to DefaultFeedLimit.
+type Feed struct {
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	Title  string `json:"title"`
+	Tag    string `json:"tag"`
+	Prefix string `json:"prefix"`
+	Limit  int    `json:"limit"`
+}
+
+const DefaultFeedLimit = 20
+
+// DefaultFeeds are served by sites that have not configured their feeds.
+var DefaultFeeds = []Feed{
+	{Path: "/feed.xml", Format: FeedFormatRSS},
+	{Path: "/atom.xml", Format: FeedFormatAtom},
+	{Path: "/feed.json", Format: FeedFormatJSON},
+	{Path: "/tags/:tag/feed.xml", Format: FeedFormatRSS},
+	{Path: "/tags/:tag/atom.xml", Format: FeedFormatAtom},
+	{Path: "/tags/:tag/feed.json", Format: FeedFormatJSON},
 }
 
 type Version struct {
-	UID   ulid.ULID            `json:"uid"`
-	Pages map[string]ulid.ULID `json:"pages"`
-	Theme ulid.ULID            `json:"theme"`
+	UID         ulid.ULID            `json:"uid"`
+	Pages       map[string]ulid.ULID `json:"pages"`
+	Theme       ulid.ULID            `json:"theme"`
+	Redirects   []Redirect           `json:"redirects"`
+	Base        ulid.ULID            `json:"base"`
+	CreatedAt   time.Time            `json:"createdAt"`
+	CreatedBy   string               `json:"createdBy"`
+	Message     string               `json:"message"`
+	PublishedAt time.Time            `json:"publishedAt"`
+	PublishedBy string               `json:"publishedBy"`
+}
+
+const (
+	RedirectKindExact  = "exact"
+	RedirectKindPrefix = "prefix"
+	RedirectKindRegex  = "regex"
+)
+
+// Redirect sends requests matching From to To. An exact redirect matches the whole path, a prefix redirect replaces
+// the matched prefix of the path with To, and a regex redirect expands To with the groups matched by From.
+type Redirect struct {
+	UID    ulid.ULID `json:"uid"`
+	Kind   string    `json:"kind"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Status int       `json:"status"`
 }
 
 type Page struct {
@@ -28,6 +100,14 @@ type Page struct {
 	TemplateName string    `json:"templateName"`
 	Tags         []string  `json:"tags"`
 	Excerpt      string    `json:"excerpt"`
+	// ContentType is the format of the content blob: html, markdown, blocks or a registered content type. An empty
+	// content type is HTML.
+	ContentType string `json:"contentType"`
+	// NoIndex excludes the page from the sitemap and search.
+	NoIndex bool `json:"noIndex"`
+	// CreatedAt is kept when a page is copied into another version, so it is the time the page was first written.
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 type WritablePage struct {
@@ -36,8 +116,46 @@ type WritablePage struct {
 	TemplateName string   `json:"templateName"`
 	Tags         []string `json:"tags"`
 	Excerpt      string   `json:"excerpt"`
+	ContentType  string   `json:"contentType"`
+	NoIndex      bool     `json:"noIndex"`
+}
+
+const (
+	ContentTypeHTML     = "html"
+	ContentTypeMarkdown = "markdown"
+	ContentTypeBlocks   = "blocks"
+)
+
+// BlockDocumentVersion is the version of the block document format written by this release.
+const BlockDocumentVersion = 1
+
+// BlockDocument is page content made of blocks, stored as JSON.
+type BlockDocument struct {
+	Version int     `json:"version"`
+	Blocks  []Block `json:"blocks"`
+}
+
+// Block is one block of a BlockDocument. The fields of Data depend on the type of the block. Columns holds the blocks
+// of each column of a columns block.
+type Block struct {
+	Type    string         `json:"type"`
+	ID      string         `json:"id,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+	Columns [][]Block      `json:"columns,omitempty"`
 }
 
+const (
+	BlockTypeParagraph = "paragraph"
+	BlockTypeHeading   = "heading"
+	BlockTypeImage     = "image"
+	BlockTypeList      = "list"
+	BlockTypeQuote     = "quote"
+	BlockTypeCode      = "code"
+	BlockTypeEmbed     = "embed"
+	BlockTypeColumns   = "columns"
+	BlockTypeCustom    = "custom"
+)
+
 type Blob struct {
 	UID  ulid.ULID `json:"uid"`
 	Hash []byte    `json:"hash"`
@@ -57,12 +175,199 @@ type Theme struct {
 	Templates map[string]ulid.ULID `json:"templates"`
 	CSSAssets []ulid.ULID          `json:"cssAssets"`
 	JSAssets  []ulid.ULID          `json:"jsAssets"`
+	// AssetOptions control how the CSS and JS assets are served.
+	AssetOptions ThemeAssetOptions `json:"assetOptions"`
+}
+
+type ThemeAssetOptions struct {
+	// Concat serves all CSS assets as one file and all JS assets as one file.
+	Concat bool `json:"concat"`
+	// Minify strips comments and whitespace from CSS, and blank lines and line comments from JS.
+	Minify bool `json:"minify"`
+}
+
+// VersionDiff describes the changes needed to go from the version From to the version To.
+type VersionDiff struct {
+	From            ulid.ULID    `json:"from"`
+	To              ulid.ULID    `json:"to"`
+	Added           []PageChange `json:"added"`
+	Removed         []PageChange `json:"removed"`
+	Moved           []PageChange `json:"moved"`
+	ContentChanged  []PageChange `json:"contentChanged"`
+	MetadataChanged []PageChange `json:"metadataChanged"`
+	Theme           *ThemeChange `json:"theme"`
+}
+
+// PageChange is a single page entry in a VersionDiff. The previous fields are empty for added pages and the current
+// fields are empty for removed pages.
+type PageChange struct {
+	Path         string    `json:"path"`
+	UID          ulid.ULID `json:"uid"`
+	PreviousPath string    `json:"previousPath"`
+	PreviousUID  ulid.ULID `json:"previousUID"`
+	Fields       []string  `json:"fields,omitempty"`
+}
+
+// MergeConflict lists what both sides of a merge changed.
+type MergeConflict struct {
+	Paths []string    `json:"paths"`
+	Pages []ulid.ULID `json:"pages"`
+	Theme bool        `json:"theme"`
+}
+
+// ThemeChange lists the templates that differ between two themes by name.
+type ThemeChange struct {
+	From             ulid.ULID `json:"from"`
+	To               ulid.ULID `json:"to"`
+	TemplatesAdded   []string  `json:"templatesAdded"`
+	TemplatesRemoved []string  `json:"templatesRemoved"`
+	TemplatesChanged []string  `json:"templatesChanged"`
+	AssetsChanged    bool      `json:"assetsChanged"`
+}
+
+// RenderCacheStats describes the use of the rendered output cache since the server started.
+type RenderCacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"maxBytes"`
+}
+
+// SearchDocument is a page as stored in the search index. Terms holds the frequency of each term of the page, Length
+// is the number of terms, and Text is the plain text that snippets are taken from.
+type SearchDocument struct {
+	Page   ulid.ULID      `json:"page"`
+	Terms  map[string]int `json:"terms"`
+	Length int            `json:"length"`
+	Text   string         `json:"text"`
+}
+
+// SearchStats describes the documents in the search index of a version.
+type SearchStats struct {
+	Documents int `json:"documents"`
+	Length    int `json:"length"`
+}
+
+// SearchPostings are the pages of a version that have any of the terms of a search. Terms holds the frequency of each
+// term by page, and Lengths the number of terms of each page.
+type SearchPostings struct {
+	Stats   SearchStats
+	Terms   map[string]map[ulid.ULID]int
+	Lengths map[ulid.ULID]int
+}
+
+// SearchResults is a page of the results of a search, ranked by relevance.
+type SearchResults struct {
+	Query     string         `json:"query"`
+	Total     int            `json:"total"`
+	Page      int            `json:"page"`
+	PageCount int            `json:"pageCount"`
+	Results   []SearchResult `json:"results"`
+}
+
+// SearchResult is a page that matches a search. Snippet is HTML with the matched words in mark elements.
+type SearchResult struct {
+	Page    ulid.ULID `json:"page"`
+	Title   string    `json:"title"`
+	Path    string    `json:"path"`
+	Excerpt string    `json:"excerpt"`
+	Snippet string    `json:"snippet"`
+	Score   float64   `json:"score"`
+}
+
+// GCReport lists everything that a garbage collection run deleted, or would delete when DryRun is set. Orphans are
+// stored blob files that no blob record points to.
+type GCReport struct {
+	DryRun   bool        `json:"dryRun"`
+	Versions []ulid.ULID `json:"versions"`
+	Pages    []ulid.ULID `json:"pages"`
+	Themes   []ulid.ULID `json:"themes"`
+	Blobs    []ulid.ULID `json:"blobs"`
+	Orphans  []ulid.ULID `json:"orphans"`
+}
+
+// ExportReport describes a static export of a version. Skipped lists the paths that have no single file to export,
+// such as pages with params, and Failures the paths that did not render.
+type ExportReport struct {
+	Version  ulid.ULID       `json:"version"`
+	Files    []string        `json:"files"`
+	Skipped  []string        `json:"skipped"`
+	Failures []ExportFailure `json:"failures"`
+}
+
+type ExportFailure struct {
+	Path    string `json:"path"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// ImportReport describes an import of files into the next version. Created and Updated list page paths, and Skipped
+// the files that were unchanged or could not be imported.
+type ImportReport struct {
+	Version ulid.ULID    `json:"version"`
+	Created []string     `json:"created"`
+	Updated []string     `json:"updated"`
+	Skipped []ImportSkip `json:"skipped"`
+}
+
+type ImportSkip struct {
+	File   string `json:"file"`
+	Path   string `json:"path,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Preview grants read access to an unpublished version until it expires or is deleted. Version is either "next", a
+// draft name or a version UID, the same as the X-Pagebin-Version header.
+type Preview struct {
+	UID       ulid.ULID `json:"uid"`
+	Version   string    `json:"version"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Token     string    `json:"token"`
+}
+
+const (
+	ReleaseKindPublish  = "publish"
+	ReleaseKindRollback = "rollback"
+	ReleaseKindMerge    = "merge"
+)
+
+// Release records a change of the current version of the site.
+type Release struct {
+	UID             ulid.ULID `json:"uid"`
+	Kind            string    `json:"kind"`
+	Version         ulid.ULID `json:"version"`
+	PreviousVersion ulid.ULID `json:"previousVersion"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+const (
+	ScheduleStatusPending = "pending"
+	ScheduleStatusDone    = "done"
+	ScheduleStatusFailed  = "failed"
+	ScheduleStatusSkipped = "skipped"
+)
+
+// Schedule is a job that publishes a version at a future time.
+type Schedule struct {
+	UID       ulid.ULID `json:"uid"`
+	Version   ulid.ULID `json:"version"`
+	RunAt     time.Time `json:"runAt"`
+	CreatedBy string    `json:"createdBy"`
+	Message   string    `json:"message"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error"`
+	RanAt     time.Time `json:"ranAt"`
 }
 
 type TargetVersion struct {
 	uid     ulid.ULID
 	current bool
 	next    bool
+	draft   string
 }
 
 func (v TargetVersion) UID() ulid.ULID {
@@ -77,6 +382,20 @@ func (v TargetVersion) IsNext() bool {
 	return v.next
 }
 
+func (v TargetVersion) IsDraft() bool {
+	return v.draft != ""
+}
+
+// Draft returns the name of the draft, or an empty string when the target is not a draft.
+func (v TargetVersion) Draft() string {
+	return v.draft
+}
+
+// IsWritable reports whether pages may be changed in the target version.
+func (v TargetVersion) IsWritable() bool {
+	return v.next || v.draft != ""
+}
+
 func NewTargetVersion(uid ulid.ULID) *TargetVersion {
 	return &TargetVersion{uid: uid}
 }
@@ -94,3 +413,10 @@ func NewNextTargetVersion(uid ulid.ULID) *TargetVersion {
 		next: true,
 	}
 }
+
+func NewDraftTargetVersion(uid ulid.ULID, name string) *TargetVersion {
+	return &TargetVersion{
+		uid:   uid,
+		draft: name,
+	}
+}
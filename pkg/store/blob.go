@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/aarongodin/pagebin/pkg/config"
 	"github.com/aarongodin/pagebin/pkg/core"
@@ -22,6 +23,12 @@ type BlobStore interface {
 	GetBytes(ctx context.Context, uid ulid.ULID) ([]byte, error)
 	CreateBlob(ctx context.Context, raw []byte) (core.Blob, error)
 	UpdateBlob(ctx context.Context, uid ulid.ULID, raw []byte) (core.Blob, error)
+	GetBlobUIDs(ctx context.Context) ([]ulid.ULID, error)
+	// GetOrphanUIDs returns the UIDs of stored bytes that have no blob record and were last written before the given
+	// time. These are left behind by writes whose transaction did not commit.
+	GetOrphanUIDs(ctx context.Context, before time.Time) ([]ulid.ULID, error)
+	DeleteBlob(ctx context.Context, uid ulid.ULID) error
+	DeleteBytes(ctx context.Context, uid ulid.ULID) error
 }
 
 type localFSBlobStore struct {
@@ -75,6 +82,64 @@ func (s localFSBlobStore) UpdateBlob(ctx context.Context, uid ulid.ULID, raw []b
 	return blob, nil
 }
 
+func (s localFSBlobStore) GetBlobUIDs(ctx context.Context) ([]ulid.ULID, error) {
+	keys, err := s.db.Keys(ctx, bucketBlobs)
+	if err != nil {
+		return nil, err
+	}
+	return parseULIDKeys(keys)
+}
+
+func (s localFSBlobStore) GetOrphanUIDs(ctx context.Context, before time.Time) ([]ulid.ULID, error) {
+	keys, err := s.db.Keys(ctx, bucketBlobs)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		records[key] = struct{}{}
+	}
+	entries, err := os.ReadDir(s.rootDir)
+	if err != nil {
+		return nil, err
+	}
+	orphans := []ulid.ULID{}
+	for _, entry := range entries {
+		if _, ok := records[entry.Name()]; ok || !entry.Type().IsRegular() {
+			continue
+		}
+		// files that are not named by a UID were not written by the blob store
+		uid, err := ulid.ParseStrict(entry.Name())
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if info.ModTime().Before(before) {
+			orphans = append(orphans, uid)
+		}
+	}
+	return orphans, nil
+}
+
+// DeleteBlob removes the blob record. The stored bytes are removed separately with DeleteBytes so that callers can
+// wait for the transaction deleting the record to commit.
+func (s localFSBlobStore) DeleteBlob(ctx context.Context, uid ulid.ULID) error {
+	return s.db.Delete(ctx, bucketBlobs, uid.String())
+}
+
+func (s localFSBlobStore) DeleteBytes(ctx context.Context, uid ulid.ULID) error {
+	if err := os.Remove(filepath.Join(s.rootDir, uid.String())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 func NewBlobStore(rc *config.RuntimeConfig, db *bolt.DB) (BlobStore, error) {
 	switch rc.BlobBackend {
 	case BlobBackendLocalFS:
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
 	bolt "go.etcd.io/bbolt"
 )
 
@@ -14,7 +15,11 @@ var (
 	bucketVersions          = "versions"
 	bucketBlobs             = "blobs"
 	bucketIndex             = "index"
-	buckets                 = [...]string{bucketApp, bucketThemes, bucketPages, bucketVersions, bucketBlobs, bucketIndex}
+	bucketReleases          = "releases"
+	bucketSchedules         = "schedules"
+	bucketPreviews          = "previews"
+	bucketSearch            = "search"
+	buckets                 = [...]string{bucketApp, bucketThemes, bucketPages, bucketVersions, bucketBlobs, bucketIndex, bucketReleases, bucketSchedules, bucketPreviews, bucketSearch}
 	bucketIndexPageVersions = "page-versions"
 	nestedBuckets           = map[string]string{
 		bucketIndex: bucketIndexPageVersions,
@@ -22,9 +27,32 @@ var (
 
 	contextKeyTransaction         = core.ContextKey("transaction")
 	contextKeyTransactionWritable = core.ContextKey("transaction-writable")
+	contextKeyTransactionJoined   = core.ContextKey("transaction-joined")
 )
 
+// parseULIDKeys converts the keys of a bucket keyed by UID.
+func parseULIDKeys(keys []string) ([]ulid.ULID, error) {
+	uids := make([]ulid.ULID, 0, len(keys))
+	for _, k := range keys {
+		uid, err := ulid.Parse(k)
+		if err != nil {
+			return nil, err
+		}
+		uids = append(uids, uid)
+	}
+	return uids, nil
+}
+
+// StartTx begins a transaction and returns a context that holds it. When ctx already holds a transaction, the returned
+// context joins it instead, so that several operations can be batched into one transaction; only the EndTx of the
+// transaction that began it commits or rolls back.
 func (s *store) StartTx(ctx context.Context, writable bool) (context.Context, error) {
+	if outerWritable, ok := ctx.Value(contextKeyTransactionWritable).(bool); ok {
+		if writable && !outerWritable {
+			return nil, core.ErrTransactionPrivilege.New("expected transaction to be writable")
+		}
+		return context.WithValue(ctx, contextKeyTransactionJoined, true), nil
+	}
 	tx, err := s.db.Begin(writable)
 	if err != nil {
 		return nil, err
@@ -43,9 +71,13 @@ func (s *store) EndTx(ctx context.Context, txErr error) error {
 	if !writableOK {
 		return core.ErrTransactionNotFound.NewWithNoMessage()
 	}
+	if joined, _ := ctx.Value(contextKeyTransactionJoined).(bool); joined {
+		return txErr
+	}
 
 	if txErr != nil {
-		if err := tx.Rollback(); err != nil {
+		// an operation that failed within the transaction may have rolled it back already
+		if err := tx.Rollback(); err != nil && err != bolt.ErrTxClosed {
 			return core.ErrTransactionEnd.Wrap(err, "failed ending transaction; original err: %s", txErr.Error())
 		}
 		return txErr
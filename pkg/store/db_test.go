@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestJoinedTransaction(t *testing.T) {
+	withTestDB(t, bucketName, func(db *bbolt.DB) {
+		s := &store{db: db}
+		items := docDB[testItem]{db}
+		ctx := context.Background()
+
+		outer, err := s.StartTx(ctx, true)
+		require.NoError(t, err)
+		inner, err := s.StartTx(outer, true)
+		require.NoError(t, err)
+		require.NoError(t, items.Save(inner, bucketName, "one", "one"))
+		require.NoError(t, s.EndTx(inner, nil), "a joined transaction does not commit")
+		rollback := errors.New("rollback")
+		assert.Equal(t, rollback, s.EndTx(outer, rollback))
+		keys, err := items.Keys(ctx, bucketName)
+		require.NoError(t, err)
+		assert.Empty(t, keys, "the outer transaction rolls back the joined writes")
+
+		outer, err = s.StartTx(ctx, true)
+		require.NoError(t, err)
+		inner, err = s.StartTx(outer, true)
+		require.NoError(t, err)
+		require.NoError(t, items.Save(inner, bucketName, "two", "two"))
+		require.NoError(t, s.EndTx(inner, nil))
+		require.NoError(t, s.EndTx(outer, nil))
+		keys, err = items.Keys(ctx, bucketName)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"two"}, keys)
+
+		outer, err = s.StartTx(ctx, false)
+		require.NoError(t, err)
+		_, err = s.StartTx(outer, true)
+		assert.Error(t, err, "a read transaction cannot be joined for writing")
+		require.NoError(t, s.EndTx(outer, nil))
+	})
+}
+
+func TestEndTxReturnsErrorOfRolledBackTransaction(t *testing.T) {
+	withTestDB(t, bucketName, func(db *bbolt.DB) {
+		s := &store{db: db}
+		items := docDB[testItem]{db}
+		ctx, err := s.StartTx(context.Background(), true)
+		require.NoError(t, err)
+		_, err = items.One(ctx, bucketName, "missing")
+		require.Error(t, err)
+		assert.Equal(t, err, s.EndTx(ctx, err), "the error is not hidden when the failed operation rolled back")
+	})
+}
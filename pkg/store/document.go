@@ -14,7 +14,10 @@ import (
 type documentDB[T any] interface {
 	One(ctx context.Context, bucket string, key string) (T, error)
 	Many(ctx context.Context, bucket string, start *string, count int) ([]T, *string, error)
+	All(ctx context.Context, bucket string) ([]T, error)
+	Keys(ctx context.Context, bucket string) ([]string, error)
 	Save(ctx context.Context, bucket string, key string, item T) error
+	Delete(ctx context.Context, bucket string, key string) error
 }
 
 type docDB[T any] struct {
@@ -101,6 +104,45 @@ func (d docDB[T]) Many(ctx context.Context, bucket string, start *string, count
 	return items, nextItemKeyString, nil
 }
 
+func (d docDB[T]) All(ctx context.Context, bucket string) ([]T, error) {
+	items := make([]T, 0)
+	if err := transactCtx(ctx, d.db, false, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return core.ErrBucketNotFound.New("bucket %s does not exist", bucket)
+		}
+		return b.ForEach(func(_, v []byte) error {
+			var item T
+			decoder := gob.NewDecoder(bytes.NewBuffer(v))
+			if err := decoder.Decode(&item); err != nil {
+				return err
+			}
+			items = append(items, item)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (d docDB[T]) Keys(ctx context.Context, bucket string) ([]string, error) {
+	keys := make([]string, 0)
+	if err := transactCtx(ctx, d.db, false, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return core.ErrBucketNotFound.New("bucket %s does not exist", bucket)
+		}
+		return b.ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
 func (d docDB[T]) Save(ctx context.Context, bucket string, key string, item T) error {
 	var buffer bytes.Buffer
 	encoder := gob.NewEncoder(&buffer)
@@ -115,3 +157,16 @@ func (d docDB[T]) Save(ctx context.Context, bucket string, key string, item T) e
 		return b.Put([]byte(key), buffer.Bytes())
 	})
 }
+
+func (d docDB[T]) Delete(ctx context.Context, bucket string, key string) error {
+	return transactCtx(ctx, d.db, true, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return core.ErrBucketNotFound.New("bucket %s does not exist", bucket)
+		}
+		if b.Get([]byte(key)) == nil {
+			return core.ErrItemNotFound.New("item %s/%s not found", bucket, key)
+		}
+		return b.Delete([]byte(key))
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/joomcode/errorx"
 	"github.com/oklog/ulid/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -99,3 +100,30 @@ func TestDocumentMany(t *testing.T) {
 		})
 	})
 }
+
+func TestDocumentAll(t *testing.T) {
+	withTestDB(t, bucketName, func(db *bbolt.DB) {
+		testDocDB := docDB[testItem]{db}
+		items, err := testDocDB.All(context.Background(), bucketName)
+		assert.NoError(t, err)
+		assert.Equal(t, []testItem{}, items)
+		for _, i := range []testItem{"one", "two", "three"} {
+			require.NoError(t, testDocDB.Save(context.Background(), bucketName, ulid.Make().String(), i))
+		}
+		items, err = testDocDB.All(context.Background(), bucketName)
+		assert.NoError(t, err)
+		assert.Equal(t, []testItem{"one", "two", "three"}, items)
+	})
+}
+
+func TestDocumentDelete(t *testing.T) {
+	withTestDB(t, bucketName, func(db *bbolt.DB) {
+		testDocDB := docDB[testItem]{db}
+		key := ulid.Make().String()
+		require.NoError(t, testDocDB.Save(context.Background(), bucketName, key, "one"))
+		assert.NoError(t, testDocDB.Delete(context.Background(), bucketName, key))
+		_, err := testDocDB.One(context.Background(), bucketName, key)
+		assert.True(t, errorx.IsNotFound(err))
+		assert.True(t, errorx.IsNotFound(testDocDB.Delete(context.Background(), bucketName, key)))
+	})
+}
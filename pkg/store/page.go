@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/aarongodin/pagebin/pkg/core"
 	"github.com/oklog/ulid/v2"
@@ -11,20 +12,27 @@ import (
 const pageStorePageSize = 50
 
 type PageStore interface {
-	PutPage(ctx context.Context, uid *ulid.ULID, write core.WritablePage, content ulid.ULID) (core.Page, error)
+	// PutPage creates or replaces a page. A zero createdAt is the time of the write.
+	PutPage(ctx context.Context, uid *ulid.ULID, write core.WritablePage, content ulid.ULID, createdAt time.Time) (core.Page, error)
 	GetPage(ctx context.Context, uid ulid.ULID) (core.Page, error)
 	GetPages(ctx context.Context, start *ulid.ULID) ([]core.Page, *ulid.ULID, error)
+	GetPageUIDs(ctx context.Context) ([]ulid.ULID, error)
+	DeletePage(ctx context.Context, uid ulid.ULID) error
 }
 
 type pageStore struct {
 	db documentDB[core.Page]
 }
 
-func (s pageStore) PutPage(ctx context.Context, uid *ulid.ULID, write core.WritablePage, content ulid.ULID) (core.Page, error) {
+func (s pageStore) PutPage(ctx context.Context, uid *ulid.ULID, write core.WritablePage, content ulid.ULID, createdAt time.Time) (core.Page, error) {
 	if uid == nil {
 		newUID := ulid.Make()
 		uid = &newUID
 	}
+	now := time.Now().UTC()
+	if createdAt.IsZero() {
+		createdAt = now
+	}
 	page := core.Page{
 		UID:          *uid,
 		Title:        write.Title,
@@ -33,6 +41,10 @@ func (s pageStore) PutPage(ctx context.Context, uid *ulid.ULID, write core.Writa
 		TemplateName: write.TemplateName,
 		Tags:         write.Tags,
 		Excerpt:      write.Excerpt,
+		ContentType:  write.ContentType,
+		NoIndex:      write.NoIndex,
+		CreatedAt:    createdAt,
+		UpdatedAt:    now,
 	}
 	if err := s.db.Save(ctx, bucketPages, page.UID.String(), page); err != nil {
 		return core.Page{}, err
@@ -56,6 +68,18 @@ func (s pageStore) GetPages(ctx context.Context, start *ulid.ULID) ([]core.Page,
 	return pages, cursorULID, nil
 }
 
+func (s pageStore) GetPageUIDs(ctx context.Context) ([]ulid.ULID, error) {
+	keys, err := s.db.Keys(ctx, bucketPages)
+	if err != nil {
+		return nil, err
+	}
+	return parseULIDKeys(keys)
+}
+
+func (s pageStore) DeletePage(ctx context.Context, uid ulid.ULID) error {
+	return s.db.Delete(ctx, bucketPages, uid.String())
+}
+
 func NewPageStore(db *bolt.DB) PageStore {
 	return &pageStore{db: docDB[core.Page]{db}}
 }
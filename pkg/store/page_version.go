@@ -15,6 +15,7 @@ type PageVersionIndex interface {
 	CreateVersion(ctx context.Context, version *core.Version) error
 	Add(ctx context.Context, pageUID ulid.ULID, versionUID ulid.ULID) error
 	Remove(ctx context.Context, pageUID ulid.ULID, versionUID ulid.ULID) error
+	Delete(ctx context.Context, pageUID ulid.ULID) error
 }
 
 type pageVersionIndex struct {
@@ -50,6 +51,16 @@ func (i pageVersionIndex) Remove(ctx context.Context, pageUID ulid.ULID, version
 	})
 }
 
+func (i pageVersionIndex) Delete(ctx context.Context, pageUID ulid.ULID) error {
+	return transactCtx(ctx, i.db, true, func(tx *bolt.Tx) error {
+		b, err := getIndexBucket(tx, bucketIndexPageVersions)
+		if err != nil {
+			return err
+		}
+		return b.Delete(pageUID.Bytes())
+	})
+}
+
 func (i pageVersionIndex) modify(ctx context.Context, pageUID ulid.ULID, fn func(versions mapset.Set[ulid.ULID])) error {
 	return transactCtx(ctx, i.db, true, func(tx *bolt.Tx) error {
 		b, err := getIndexBucket(tx, bucketIndexPageVersions)
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+const previewStorePageSize = 50
+
+type PreviewStore interface {
+	CreatePreview(ctx context.Context, version string, expiresAt time.Time, createdBy string) (core.Preview, error)
+	GetPreview(ctx context.Context, uid ulid.ULID) (core.Preview, error)
+	GetPreviews(ctx context.Context, start *ulid.ULID) ([]core.Preview, *ulid.ULID, error)
+	DeletePreview(ctx context.Context, uid ulid.ULID) error
+}
+
+type previewStore struct {
+	db documentDB[core.Preview]
+}
+
+func (s previewStore) CreatePreview(ctx context.Context, version string, expiresAt time.Time, createdBy string) (core.Preview, error) {
+	preview := core.Preview{
+		UID:       ulid.Make(),
+		Version:   version,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt.UTC(),
+	}
+	if err := s.db.Save(ctx, bucketPreviews, preview.UID.String(), preview); err != nil {
+		return core.Preview{}, err
+	}
+	return preview, nil
+}
+
+func (s previewStore) GetPreview(ctx context.Context, uid ulid.ULID) (core.Preview, error) {
+	return s.db.One(ctx, bucketPreviews, uid.String())
+}
+
+func (s previewStore) GetPreviews(ctx context.Context, start *ulid.ULID) ([]core.Preview, *ulid.ULID, error) {
+	previews, cursor, err := s.db.Many(ctx, bucketPreviews, getStringKey(start), previewStorePageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	cursorULID, err := getULIDKey(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return previews, cursorULID, nil
+}
+
+func (s previewStore) DeletePreview(ctx context.Context, uid ulid.ULID) error {
+	return s.db.Delete(ctx, bucketPreviews, uid.String())
+}
+
+func NewPreviewStore(db *bolt.DB) PreviewStore {
+	return &previewStore{db: docDB[core.Preview]{db}}
+}
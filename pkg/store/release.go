@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+const releaseStorePageSize = 50
+
+type ReleaseStore interface {
+	CreateRelease(ctx context.Context, kind string, version ulid.ULID, previousVersion ulid.ULID) (core.Release, error)
+	GetReleases(ctx context.Context, start *ulid.ULID) ([]core.Release, *ulid.ULID, error)
+}
+
+type releaseStore struct {
+	db documentDB[core.Release]
+}
+
+func (s releaseStore) CreateRelease(ctx context.Context, kind string, version ulid.ULID, previousVersion ulid.ULID) (core.Release, error) {
+	release := core.Release{
+		UID:             ulid.Make(),
+		Kind:            kind,
+		Version:         version,
+		PreviousVersion: previousVersion,
+		CreatedAt:       time.Now().UTC(),
+	}
+	if err := s.db.Save(ctx, bucketReleases, release.UID.String(), release); err != nil {
+		return core.Release{}, err
+	}
+	return release, nil
+}
+
+func (s releaseStore) GetReleases(ctx context.Context, start *ulid.ULID) ([]core.Release, *ulid.ULID, error) {
+	releases, cursor, err := s.db.Many(ctx, bucketReleases, getStringKey(start), releaseStorePageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	cursorULID, err := getULIDKey(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return releases, cursorULID, nil
+}
+
+func NewReleaseStore(db *bolt.DB) ReleaseStore {
+	return &releaseStore{db: docDB[core.Release]{db}}
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+const scheduleStorePageSize = 50
+
+type ScheduleStore interface {
+	CreateSchedule(ctx context.Context, version ulid.ULID, runAt time.Time, createdBy string, message string) (core.Schedule, error)
+	GetSchedule(ctx context.Context, uid ulid.ULID) (core.Schedule, error)
+	GetSchedules(ctx context.Context, start *ulid.ULID) ([]core.Schedule, *ulid.ULID, error)
+	GetPendingSchedules(ctx context.Context) ([]core.Schedule, error)
+	UpdateSchedule(ctx context.Context, schedule core.Schedule) (core.Schedule, error)
+	DeleteSchedule(ctx context.Context, uid ulid.ULID) error
+}
+
+type scheduleStore struct {
+	db documentDB[core.Schedule]
+}
+
+func (s scheduleStore) CreateSchedule(ctx context.Context, version ulid.ULID, runAt time.Time, createdBy string, message string) (core.Schedule, error) {
+	schedule := core.Schedule{
+		UID:       ulid.Make(),
+		Version:   version,
+		RunAt:     runAt.UTC(),
+		CreatedBy: createdBy,
+		Message:   message,
+		Status:    core.ScheduleStatusPending,
+	}
+	if err := s.db.Save(ctx, bucketSchedules, schedule.UID.String(), schedule); err != nil {
+		return core.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+func (s scheduleStore) GetSchedule(ctx context.Context, uid ulid.ULID) (core.Schedule, error) {
+	return s.db.One(ctx, bucketSchedules, uid.String())
+}
+
+func (s scheduleStore) GetSchedules(ctx context.Context, start *ulid.ULID) ([]core.Schedule, *ulid.ULID, error) {
+	schedules, cursor, err := s.db.Many(ctx, bucketSchedules, getStringKey(start), scheduleStorePageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	cursorULID, err := getULIDKey(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return schedules, cursorULID, nil
+}
+
+// GetPendingSchedules returns every schedule that has not run yet, ordered by the time it should run.
+func (s scheduleStore) GetPendingSchedules(ctx context.Context) ([]core.Schedule, error) {
+	all, err := s.db.All(ctx, bucketSchedules)
+	if err != nil {
+		return nil, err
+	}
+	pending := slices.DeleteFunc(all, func(schedule core.Schedule) bool {
+		return schedule.Status != core.ScheduleStatusPending
+	})
+	slices.SortFunc(pending, func(a, b core.Schedule) int {
+		return a.RunAt.Compare(b.RunAt)
+	})
+	return pending, nil
+}
+
+func (s scheduleStore) UpdateSchedule(ctx context.Context, schedule core.Schedule) (core.Schedule, error) {
+	if _, err := s.db.One(ctx, bucketSchedules, schedule.UID.String()); err != nil {
+		return core.Schedule{}, err
+	}
+	if err := s.db.Save(ctx, bucketSchedules, schedule.UID.String(), schedule); err != nil {
+		return core.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+func (s scheduleStore) DeleteSchedule(ctx context.Context, uid ulid.ULID) error {
+	return s.db.Delete(ctx, bucketSchedules, uid.String())
+}
+
+func NewScheduleStore(db *bolt.DB) ScheduleStore {
+	return &scheduleStore{db: docDB[core.Schedule]{db}}
+}
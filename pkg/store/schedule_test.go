@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestScheduleStore(t *testing.T) {
+	withTestDB(t, bucketSchedules, func(db *bbolt.DB) {
+		ctx := context.Background()
+		schedules := NewScheduleStore(db)
+		now := time.Now().Truncate(time.Second)
+		version := ulid.Make()
+
+		later, err := schedules.CreateSchedule(ctx, version, now.Add(time.Hour), "admin", "later")
+		require.NoError(t, err)
+		assert.Equal(t, core.ScheduleStatusPending, later.Status)
+		assert.Equal(t, time.UTC, later.RunAt.Location())
+		sooner, err := schedules.CreateSchedule(ctx, version, now.Add(time.Minute), "admin", "sooner")
+		require.NoError(t, err)
+		done, err := schedules.CreateSchedule(ctx, version, now, "admin", "done")
+		require.NoError(t, err)
+
+		got, err := schedules.GetSchedule(ctx, later.UID)
+		require.NoError(t, err)
+		assert.Equal(t, later, got)
+
+		done.Status = core.ScheduleStatusDone
+		done.RanAt = now.UTC()
+		_, err = schedules.UpdateSchedule(ctx, done)
+		require.NoError(t, err)
+		pending, err := schedules.GetPendingSchedules(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []core.Schedule{sooner, later}, pending, "pending schedules are ordered by run time")
+
+		all, cursor, err := schedules.GetSchedules(ctx, nil)
+		require.NoError(t, err)
+		assert.Nil(t, cursor)
+		assert.Len(t, all, 3)
+
+		_, err = schedules.UpdateSchedule(ctx, core.Schedule{UID: ulid.Make()})
+		assert.True(t, errorx.IsNotFound(err), "only existing schedules are updated")
+
+		require.NoError(t, schedules.DeleteSchedule(ctx, sooner.UID))
+		_, err = schedules.GetSchedule(ctx, sooner.UID)
+		assert.True(t, errorx.IsNotFound(err))
+		pending, err = schedules.GetPendingSchedules(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []core.Schedule{later}, pending)
+	})
+}
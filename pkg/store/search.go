@@ -0,0 +1,346 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/oklog/ulid/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketSearchDocuments = []byte("documents")
+	bucketSearchTerms     = []byte("terms")
+	bucketSearchLengths   = []byte("lengths")
+	keySearchStats        = []byte("stats")
+)
+
+// SearchIndex is an inverted index of the pages of each version. Each version has a bucket in the search bucket, which
+// holds the indexed documents and their lengths by page UID, and a bucket for each term with the frequency of the term
+// by page UID, so that writing a document only touches its own postings. A version without a bucket is not indexed,
+// and writes to it are ignored so that a partial index is never created; CreateVersion starts an empty index.
+type SearchIndex interface {
+	Exists(ctx context.Context, version ulid.ULID) (bool, error)
+	CreateVersion(ctx context.Context, version ulid.ULID) error
+	CopyVersion(ctx context.Context, from ulid.ULID, to ulid.ULID) error
+	DeleteVersion(ctx context.Context, version ulid.ULID) error
+	PutDocument(ctx context.Context, version ulid.ULID, doc core.SearchDocument) error
+	DeleteDocument(ctx context.Context, version ulid.ULID, page ulid.ULID) error
+	// Postings returns the pages of a version that have any of the terms, with the frequency of each term.
+	Postings(ctx context.Context, version ulid.ULID, terms []string) (core.SearchPostings, error)
+	GetDocuments(ctx context.Context, version ulid.ULID, pages []ulid.ULID) ([]core.SearchDocument, error)
+}
+
+type searchIndex struct {
+	db *bolt.DB
+}
+
+func (i searchIndex) Exists(ctx context.Context, version ulid.ULID) (bool, error) {
+	exists := false
+	err := transactCtx(ctx, i.db, false, func(tx *bolt.Tx) error {
+		b, err := getSearchBucket(tx)
+		if err != nil {
+			return err
+		}
+		exists = b.Bucket(version.Bytes()) != nil
+		return nil
+	})
+	return exists, err
+}
+
+func (i searchIndex) CreateVersion(ctx context.Context, version ulid.ULID) error {
+	return transactCtx(ctx, i.db, true, func(tx *bolt.Tx) error {
+		b, err := getSearchBucket(tx)
+		if err != nil {
+			return err
+		}
+		if err := b.DeleteBucket(version.Bytes()); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		vb, err := b.CreateBucket(version.Bytes())
+		if err != nil {
+			return err
+		}
+		for _, name := range [][]byte{bucketSearchDocuments, bucketSearchTerms, bucketSearchLengths} {
+			if _, err := vb.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (i searchIndex) CopyVersion(ctx context.Context, from ulid.ULID, to ulid.ULID) error {
+	return transactCtx(ctx, i.db, true, func(tx *bolt.Tx) error {
+		b, err := getSearchBucket(tx)
+		if err != nil {
+			return err
+		}
+		src := b.Bucket(from.Bytes())
+		if src == nil {
+			return nil
+		}
+		dst, err := b.CreateBucket(to.Bytes())
+		if err != nil {
+			return err
+		}
+		return copyBucket(src, dst)
+	})
+}
+
+func (i searchIndex) DeleteVersion(ctx context.Context, version ulid.ULID) error {
+	return transactCtx(ctx, i.db, true, func(tx *bolt.Tx) error {
+		b, err := getSearchBucket(tx)
+		if err != nil {
+			return err
+		}
+		if err := b.DeleteBucket(version.Bytes()); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		return nil
+	})
+}
+
+func (i searchIndex) PutDocument(ctx context.Context, version ulid.ULID, doc core.SearchDocument) error {
+	return transactCtx(ctx, i.db, true, func(tx *bolt.Tx) error {
+		vb, err := getSearchVersionBucket(tx, version)
+		if err != nil || vb == nil {
+			return err
+		}
+		if err := removeSearchDocument(vb, doc.Page); err != nil {
+			return err
+		}
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if err := vb.Bucket(bucketSearchDocuments).Put(doc.Page.Bytes(), raw); err != nil {
+			return err
+		}
+		if err := vb.Bucket(bucketSearchLengths).Put(doc.Page.Bytes(), []byte(strconv.Itoa(doc.Length))); err != nil {
+			return err
+		}
+		terms := vb.Bucket(bucketSearchTerms)
+		for term, frequency := range doc.Terms {
+			if term == "" {
+				continue
+			}
+			postings, err := terms.CreateBucketIfNotExists([]byte(term))
+			if err != nil {
+				return err
+			}
+			if err := postings.Put(doc.Page.Bytes(), []byte(strconv.Itoa(frequency))); err != nil {
+				return err
+			}
+		}
+		return modifySearchStats(vb, func(stats *core.SearchStats) {
+			stats.Documents++
+			stats.Length += doc.Length
+		})
+	})
+}
+
+func (i searchIndex) DeleteDocument(ctx context.Context, version ulid.ULID, page ulid.ULID) error {
+	return transactCtx(ctx, i.db, true, func(tx *bolt.Tx) error {
+		vb, err := getSearchVersionBucket(tx, version)
+		if err != nil || vb == nil {
+			return err
+		}
+		return removeSearchDocument(vb, page)
+	})
+}
+
+func (i searchIndex) Postings(ctx context.Context, version ulid.ULID, terms []string) (core.SearchPostings, error) {
+	postings := core.SearchPostings{
+		Terms:   make(map[string]map[ulid.ULID]int, len(terms)),
+		Lengths: map[ulid.ULID]int{},
+	}
+	err := transactCtx(ctx, i.db, false, func(tx *bolt.Tx) error {
+		vb, err := getSearchVersionBucket(tx, version)
+		if err != nil {
+			return err
+		}
+		if vb == nil {
+			return core.ErrSearchIndexNotFound.New("version %s is not indexed", version.String())
+		}
+		if postings.Stats, err = getSearchStats(vb); err != nil {
+			return err
+		}
+		lengths := vb.Bucket(bucketSearchLengths)
+		for _, term := range terms {
+			p, err := getPostings(vb.Bucket(bucketSearchTerms), term)
+			if err != nil {
+				return err
+			}
+			postings.Terms[term] = p
+			for page := range p {
+				if _, ok := postings.Lengths[page]; ok {
+					continue
+				}
+				length, err := strconv.Atoi(string(lengths.Get(page.Bytes())))
+				if err != nil {
+					return core.ErrSearchIndexNotFound.Wrap(err, "page %s has no length", page.String())
+				}
+				postings.Lengths[page] = length
+			}
+		}
+		return nil
+	})
+	return postings, err
+}
+
+func (i searchIndex) GetDocuments(ctx context.Context, version ulid.ULID, pages []ulid.ULID) ([]core.SearchDocument, error) {
+	docs := make([]core.SearchDocument, 0, len(pages))
+	err := transactCtx(ctx, i.db, false, func(tx *bolt.Tx) error {
+		vb, err := getSearchVersionBucket(tx, version)
+		if err != nil {
+			return err
+		}
+		if vb == nil {
+			return core.ErrSearchIndexNotFound.New("version %s is not indexed", version.String())
+		}
+		for _, page := range pages {
+			raw := vb.Bucket(bucketSearchDocuments).Get(page.Bytes())
+			if raw == nil {
+				return core.ErrSearchIndexNotFound.New("page %s is not indexed", page.String())
+			}
+			var doc core.SearchDocument
+			if err := json.Unmarshal(raw, &doc); err != nil {
+				return err
+			}
+			docs = append(docs, doc)
+		}
+		return nil
+	})
+	return docs, err
+}
+
+func getSearchBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	b := tx.Bucket([]byte(bucketSearch))
+	if b == nil {
+		return nil, core.ErrBucketNotFound.New("bucket %s does not exist", bucketSearch)
+	}
+	return b, nil
+}
+
+// getSearchVersionBucket returns the index bucket of a version, or nil when the version is not indexed.
+func getSearchVersionBucket(tx *bolt.Tx, version ulid.ULID) (*bolt.Bucket, error) {
+	b, err := getSearchBucket(tx)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bucket(version.Bytes()), nil
+}
+
+func removeSearchDocument(vb *bolt.Bucket, page ulid.ULID) error {
+	docs := vb.Bucket(bucketSearchDocuments)
+	raw := docs.Get(page.Bytes())
+	if raw == nil {
+		return nil
+	}
+	var doc core.SearchDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	terms := vb.Bucket(bucketSearchTerms)
+	for term := range doc.Terms {
+		if err := removePosting(terms, term, page); err != nil {
+			return err
+		}
+	}
+	if err := docs.Delete(page.Bytes()); err != nil {
+		return err
+	}
+	if err := vb.Bucket(bucketSearchLengths).Delete(page.Bytes()); err != nil {
+		return err
+	}
+	return modifySearchStats(vb, func(stats *core.SearchStats) {
+		stats.Documents--
+		stats.Length -= doc.Length
+	})
+}
+
+func getPostings(terms *bolt.Bucket, term string) (map[ulid.ULID]int, error) {
+	postings := map[ulid.ULID]int{}
+	if term == "" {
+		return postings, nil
+	}
+	b := terms.Bucket([]byte(term))
+	if b == nil {
+		return postings, nil
+	}
+	err := b.ForEach(func(k, v []byte) error {
+		var page ulid.ULID
+		if err := page.UnmarshalBinary(k); err != nil {
+			return err
+		}
+		frequency, err := strconv.Atoi(string(v))
+		if err != nil {
+			return err
+		}
+		postings[page] = frequency
+		return nil
+	})
+	return postings, err
+}
+
+// removePosting removes a page from the postings of a term, deleting the term once no page has it.
+func removePosting(terms *bolt.Bucket, term string, page ulid.ULID) error {
+	if term == "" {
+		return nil
+	}
+	b := terms.Bucket([]byte(term))
+	if b == nil {
+		return nil
+	}
+	if err := b.Delete(page.Bytes()); err != nil {
+		return err
+	}
+	if k, _ := b.Cursor().First(); k == nil {
+		return terms.DeleteBucket([]byte(term))
+	}
+	return nil
+}
+
+func getSearchStats(vb *bolt.Bucket) (core.SearchStats, error) {
+	var stats core.SearchStats
+	if raw := vb.Get(keySearchStats); raw != nil {
+		if err := json.Unmarshal(raw, &stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+func modifySearchStats(vb *bolt.Bucket, fn func(stats *core.SearchStats)) error {
+	stats, err := getSearchStats(vb)
+	if err != nil {
+		return err
+	}
+	fn(&stats)
+	raw, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return vb.Put(keySearchStats, raw)
+}
+
+// copyBucket copies the keys and nested buckets of src into dst.
+func copyBucket(src *bolt.Bucket, dst *bolt.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nested, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(src.Bucket(k), nested)
+	})
+}
+
+func NewSearchIndex(db *bolt.DB) SearchIndex {
+	return &searchIndex{db}
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aarongodin/pagebin/pkg/core"
+	"github.com/joomcode/errorx"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestSearchIndexPostings(t *testing.T) {
+	withTestDB(t, bucketSearch, func(db *bbolt.DB) {
+		ctx := context.Background()
+		index := NewSearchIndex(db)
+		version, one, two := ulid.Make(), ulid.Make(), ulid.Make()
+		require.NoError(t, index.CreateVersion(ctx, version))
+		require.NoError(t, index.PutDocument(ctx, version, core.SearchDocument{Page: one, Terms: map[string]int{"go": 2, "web": 1}, Length: 3}))
+		require.NoError(t, index.PutDocument(ctx, version, core.SearchDocument{Page: two, Terms: map[string]int{"go": 1}, Length: 1}))
+
+		postings, err := index.Postings(ctx, version, []string{"go", "web", "missing"})
+		require.NoError(t, err)
+		assert.Equal(t, core.SearchStats{Documents: 2, Length: 4}, postings.Stats)
+		assert.Equal(t, map[string]map[ulid.ULID]int{
+			"go":      {one: 2, two: 1},
+			"web":     {one: 1},
+			"missing": {},
+		}, postings.Terms)
+		assert.Equal(t, map[ulid.ULID]int{one: 3, two: 1}, postings.Lengths)
+
+		// writing a document again replaces its postings
+		require.NoError(t, index.PutDocument(ctx, version, core.SearchDocument{Page: one, Terms: map[string]int{"go": 5}, Length: 5}))
+		postings, err = index.Postings(ctx, version, []string{"go", "web"})
+		require.NoError(t, err)
+		assert.Equal(t, core.SearchStats{Documents: 2, Length: 6}, postings.Stats)
+		assert.Equal(t, map[ulid.ULID]int{one: 5, two: 1}, postings.Terms["go"])
+		assert.Empty(t, postings.Terms["web"])
+
+		copied := ulid.Make()
+		require.NoError(t, index.CopyVersion(ctx, version, copied))
+		require.NoError(t, index.DeleteDocument(ctx, version, one))
+		postings, err = index.Postings(ctx, version, []string{"go"})
+		require.NoError(t, err)
+		assert.Equal(t, core.SearchStats{Documents: 1, Length: 1}, postings.Stats)
+		assert.Equal(t, map[ulid.ULID]int{two: 1}, postings.Terms["go"])
+		postings, err = index.Postings(ctx, copied, []string{"go"})
+		require.NoError(t, err)
+		assert.Equal(t, map[ulid.ULID]int{one: 5, two: 1}, postings.Terms["go"], "a copy is not changed by its source")
+
+		// a term is removed once no page has it
+		require.NoError(t, index.DeleteDocument(ctx, version, two))
+		require.NoError(t, db.View(func(tx *bbolt.Tx) error {
+			vb, err := getSearchVersionBucket(tx, version)
+			require.NoError(t, err)
+			k, _ := vb.Bucket(bucketSearchTerms).Cursor().First()
+			assert.Nil(t, k)
+			return nil
+		}))
+	})
+}
+
+func TestSearchIndexIgnoresUnindexedVersions(t *testing.T) {
+	withTestDB(t, bucketSearch, func(db *bbolt.DB) {
+		ctx := context.Background()
+		index := NewSearchIndex(db)
+		version := ulid.Make()
+		require.NoError(t, index.PutDocument(ctx, version, core.SearchDocument{Page: ulid.Make(), Terms: map[string]int{"go": 1}, Length: 1}))
+		exists, err := index.Exists(ctx, version)
+		require.NoError(t, err)
+		assert.False(t, exists)
+		_, err = index.Postings(ctx, version, []string{"go"})
+		assert.True(t, errorx.IsOfType(err, core.ErrSearchIndexNotFound))
+	})
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/aarongodin/pagebin/pkg/core"
 	"github.com/oklog/ulid/v2"
@@ -15,7 +16,10 @@ var (
 type SiteStore interface {
 	GetSite(ctx context.Context) (core.Site, error)
 	CreateSite(ctx context.Context, title string, version ulid.ULID, nextVersion ulid.ULID) (core.Site, error)
-	UpdateSite(ctx context.Context, title string) (core.Site, error)
+	UpdateSite(ctx context.Context, update core.SiteUpdate) (core.Site, error)
+	SetVersions(ctx context.Context, version ulid.ULID, nextVersion ulid.ULID) (core.Site, error)
+	SetDraft(ctx context.Context, name string, version ulid.ULID) (core.Site, error)
+	DeleteDraft(ctx context.Context, name string) (core.Site, error)
 }
 
 type siteStore struct {
@@ -32,6 +36,7 @@ func (s siteStore) CreateSite(ctx context.Context, title string, version ulid.UL
 		Title:       title,
 		Version:     version,
 		NextVersion: nextVersion,
+		UpdatedAt:   time.Now().UTC(),
 	}
 	if err := s.db.Save(ctx, bucketApp, keySite, site); err != nil {
 		return core.Site{}, err
@@ -39,14 +44,77 @@ func (s siteStore) CreateSite(ctx context.Context, title string, version ulid.UL
 	return site, nil
 }
 
-func (s siteStore) UpdateSite(ctx context.Context, title string) (core.Site, error) {
+func (s siteStore) UpdateSite(ctx context.Context, update core.SiteUpdate) (core.Site, error) {
 	site, err := s.db.One(ctx, bucketApp, keySite)
 	if err != nil {
 		return core.Site{}, err
 	}
-	if title != "" {
-		site.Title = title
+	changed := false
+	if update.Title != nil && *update.Title != "" {
+		site.Title = *update.Title
+		changed = true
 	}
+	if update.URL != nil {
+		site.URL = *update.URL
+		changed = true
+	}
+	if update.Feeds != nil {
+		site.Feeds = *update.Feeds
+		changed = true
+	}
+	if update.Robots != nil {
+		site.Robots = *update.Robots
+		changed = true
+	}
+	if changed {
+		site.UpdatedAt = time.Now().UTC()
+	}
+	if err := s.db.Save(ctx, bucketApp, keySite, site); err != nil {
+		return core.Site{}, err
+	}
+	return site, nil
+}
+
+func (s siteStore) SetVersions(ctx context.Context, version ulid.ULID, nextVersion ulid.ULID) (core.Site, error) {
+	site, err := s.db.One(ctx, bucketApp, keySite)
+	if err != nil {
+		return core.Site{}, err
+	}
+	if site.Version != version {
+		site.UpdatedAt = time.Now().UTC()
+	}
+	site.Version = version
+	site.NextVersion = nextVersion
+	if err := s.db.Save(ctx, bucketApp, keySite, site); err != nil {
+		return core.Site{}, err
+	}
+	return site, nil
+}
+
+func (s siteStore) SetDraft(ctx context.Context, name string, version ulid.ULID) (core.Site, error) {
+	site, err := s.db.One(ctx, bucketApp, keySite)
+	if err != nil {
+		return core.Site{}, err
+	}
+	if site.Drafts == nil {
+		site.Drafts = map[string]ulid.ULID{}
+	}
+	site.Drafts[name] = version
+	if err := s.db.Save(ctx, bucketApp, keySite, site); err != nil {
+		return core.Site{}, err
+	}
+	return site, nil
+}
+
+func (s siteStore) DeleteDraft(ctx context.Context, name string) (core.Site, error) {
+	site, err := s.db.One(ctx, bucketApp, keySite)
+	if err != nil {
+		return core.Site{}, err
+	}
+	if _, exists := site.Drafts[name]; !exists {
+		return core.Site{}, core.ErrDraftNotFound.New("draft \"%s\" not found", name)
+	}
+	delete(site.Drafts, name)
 	if err := s.db.Save(ctx, bucketApp, keySite, site); err != nil {
 		return core.Site{}, err
 	}
@@ -2,11 +2,15 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/aarongodin/pagebin/pkg/config"
 	bolt "go.etcd.io/bbolt"
 )
 
+// openTimeout bounds how long to wait for the lock on the database file, which is held by a running server.
+const openTimeout = 5 * time.Second
+
 type Store interface {
 	DB() *bolt.DB
 	StartTx(ctx context.Context, writable bool) (context.Context, error)
@@ -17,15 +21,23 @@ type Store interface {
 	Versions() VersionStore
 	Themes() ThemeStore
 	Blobs() BlobStore
+	Releases() ReleaseStore
+	Schedules() ScheduleStore
+	Previews() PreviewStore
+	Search() SearchIndex
 }
 
 type store struct {
-	db       *bolt.DB
-	sites    SiteStore
-	pages    PageStore
-	versions VersionStore
-	themes   ThemeStore
-	blobs    BlobStore
+	db        *bolt.DB
+	sites     SiteStore
+	pages     PageStore
+	versions  VersionStore
+	themes    ThemeStore
+	blobs     BlobStore
+	releases  ReleaseStore
+	schedules ScheduleStore
+	previews  PreviewStore
+	search    SearchIndex
 }
 
 func (s *store) DB() *bolt.DB {
@@ -36,14 +48,18 @@ func (s *store) Close(_ context.Context) error {
 	return s.db.Close()
 }
 
-func (s *store) Sites() SiteStore       { return s.sites }
-func (s *store) Pages() PageStore       { return s.pages }
-func (s *store) Versions() VersionStore { return s.versions }
-func (s *store) Themes() ThemeStore     { return s.themes }
-func (s *store) Blobs() BlobStore       { return s.blobs }
+func (s *store) Sites() SiteStore         { return s.sites }
+func (s *store) Pages() PageStore         { return s.pages }
+func (s *store) Versions() VersionStore   { return s.versions }
+func (s *store) Themes() ThemeStore       { return s.themes }
+func (s *store) Blobs() BlobStore         { return s.blobs }
+func (s *store) Releases() ReleaseStore   { return s.releases }
+func (s *store) Schedules() ScheduleStore { return s.schedules }
+func (s *store) Previews() PreviewStore   { return s.previews }
+func (s *store) Search() SearchIndex      { return s.search }
 
 func NewStore(rc *config.RuntimeConfig) (Store, error) {
-	db, err := bolt.Open(rc.DatabaseFile, 0600, nil)
+	db, err := bolt.Open(rc.DatabaseFile, 0600, &bolt.Options{Timeout: openTimeout})
 	if err != nil {
 		return nil, err
 	}
@@ -68,13 +84,18 @@ func NewStore(rc *config.RuntimeConfig) (Store, error) {
 	}
 
 	pageVersions := NewPageVersionIndex(db)
+	search := NewSearchIndex(db)
 
 	return &store{
-		db:       db,
-		sites:    NewSiteStore(db),
-		pages:    NewPageStore(db),
-		versions: NewVersionStore(db, pageVersions),
-		themes:   NewThemeStore(db),
-		blobs:    blobs,
+		db:        db,
+		sites:     NewSiteStore(db),
+		pages:     NewPageStore(db),
+		versions:  NewVersionStore(db, pageVersions, search),
+		themes:    NewThemeStore(db),
+		blobs:     blobs,
+		releases:  NewReleaseStore(db),
+		schedules: NewScheduleStore(db),
+		previews:  NewPreviewStore(db),
+		search:    search,
 	}, nil
 }
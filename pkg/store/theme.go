@@ -9,20 +9,23 @@ import (
 )
 
 type ThemeStore interface {
-	CreateTheme(ctx context.Context, templates map[string]ulid.ULID, cssAssets []ulid.ULID, jsAssets []ulid.ULID) (core.Theme, error)
+	CreateTheme(ctx context.Context, templates map[string]ulid.ULID, cssAssets []ulid.ULID, jsAssets []ulid.ULID, assetOptions core.ThemeAssetOptions) (core.Theme, error)
 	GetTheme(ctx context.Context, uid ulid.ULID) (core.Theme, error)
+	GetThemeUIDs(ctx context.Context) ([]ulid.ULID, error)
+	DeleteTheme(ctx context.Context, uid ulid.ULID) error
 }
 
 type themeStore struct {
 	db documentDB[core.Theme]
 }
 
-func (s themeStore) CreateTheme(ctx context.Context, templates map[string]ulid.ULID, cssAssets []ulid.ULID, jsAssets []ulid.ULID) (core.Theme, error) {
+func (s themeStore) CreateTheme(ctx context.Context, templates map[string]ulid.ULID, cssAssets []ulid.ULID, jsAssets []ulid.ULID, assetOptions core.ThemeAssetOptions) (core.Theme, error) {
 	theme := core.Theme{
-		UID:       ulid.Make(),
-		Templates: templates,
-		CSSAssets: cssAssets,
-		JSAssets:  jsAssets,
+		UID:          ulid.Make(),
+		Templates:    templates,
+		CSSAssets:    cssAssets,
+		JSAssets:     jsAssets,
+		AssetOptions: assetOptions,
 	}
 	if err := s.db.Save(ctx, bucketThemes, theme.UID.String(), theme); err != nil {
 		return core.Theme{}, err
@@ -34,6 +37,18 @@ func (s themeStore) GetTheme(ctx context.Context, uid ulid.ULID) (core.Theme, er
 	return s.db.One(ctx, bucketThemes, uid.String())
 }
 
+func (s themeStore) GetThemeUIDs(ctx context.Context) ([]ulid.ULID, error) {
+	keys, err := s.db.Keys(ctx, bucketThemes)
+	if err != nil {
+		return nil, err
+	}
+	return parseULIDKeys(keys)
+}
+
+func (s themeStore) DeleteTheme(ctx context.Context, uid ulid.ULID) error {
+	return s.db.Delete(ctx, bucketThemes, uid.String())
+}
+
 func NewThemeStore(db *bolt.DB) ThemeStore {
 	return &themeStore{db: docDB[core.Theme]{db}}
 }
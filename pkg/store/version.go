@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/aarongodin/pagebin/pkg/core"
 	mapset "github.com/deckarep/golang-set/v2"
@@ -10,24 +11,36 @@ import (
 )
 
 type VersionStore interface {
-	CreateVersion(ctx context.Context, pages map[string]ulid.ULID, theme ulid.ULID) (core.Version, error)
+	CreateVersion(ctx context.Context, pages map[string]ulid.ULID, theme ulid.ULID, createdBy string) (core.Version, error)
 	GetVersion(ctx context.Context, uid ulid.ULID) (core.Version, error)
+	GetVersions(ctx context.Context, start *ulid.ULID) ([]core.Version, *ulid.ULID, error)
+	GetAllVersions(ctx context.Context) ([]core.Version, error)
+	DeleteVersion(ctx context.Context, uid ulid.ULID) error
+	DeletePageIndex(ctx context.Context, pageUID ulid.ULID) error
+	MarkPublished(ctx context.Context, uid ulid.ULID, publishedBy string, message string) (core.Version, error)
+	SetRedirects(ctx context.Context, uid ulid.ULID, redirects []core.Redirect) (core.Version, error)
+	SetBase(ctx context.Context, uid ulid.ULID, base ulid.ULID) (core.Version, error)
 	SetPage(ctx context.Context, uid ulid.ULID, previousPath string, path string, pageUID ulid.ULID) (core.Version, error)
 	UnsetPage(ctx context.Context, uid ulid.ULID, path string, pageUID ulid.ULID) (core.Version, error)
-	Clone(ctx context.Context, uid ulid.ULID) (core.Version, error)
+	Clone(ctx context.Context, uid ulid.ULID, createdBy string) (core.Version, error)
 	GetPageVersions(ctx context.Context, pageUID ulid.ULID) (mapset.Set[ulid.ULID], error)
 }
 
+const versionStorePageSize = 50
+
 type versionStore struct {
 	db           documentDB[core.Version]
 	pageVersions PageVersionIndex
+	search       SearchIndex
 }
 
-func (s versionStore) CreateVersion(ctx context.Context, pages map[string]ulid.ULID, theme ulid.ULID) (core.Version, error) {
+func (s versionStore) CreateVersion(ctx context.Context, pages map[string]ulid.ULID, theme ulid.ULID, createdBy string) (core.Version, error) {
 	version := core.Version{
-		UID:   ulid.Make(),
-		Pages: pages,
-		Theme: theme,
+		UID:       ulid.Make(),
+		Pages:     pages,
+		Theme:     theme,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: createdBy,
 	}
 	if err := s.db.Save(ctx, bucketVersions, version.UID.String(), version); err != nil {
 		return core.Version{}, err
@@ -42,6 +55,86 @@ func (s versionStore) GetVersion(ctx context.Context, uid ulid.ULID) (core.Versi
 	return s.db.One(ctx, bucketVersions, uid.String())
 }
 
+func (s versionStore) GetVersions(ctx context.Context, start *ulid.ULID) ([]core.Version, *ulid.ULID, error) {
+	versions, cursor, err := s.db.Many(ctx, bucketVersions, getStringKey(start), versionStorePageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	cursorULID, err := getULIDKey(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return versions, cursorULID, nil
+}
+
+func (s versionStore) GetAllVersions(ctx context.Context) ([]core.Version, error) {
+	return s.db.All(ctx, bucketVersions)
+}
+
+// DeleteVersion removes the version and removes it from the page-versions index of each of its pages.
+func (s versionStore) DeleteVersion(ctx context.Context, uid ulid.ULID) error {
+	version, err := s.db.One(ctx, bucketVersions, uid.String())
+	if err != nil {
+		return err
+	}
+	for _, pageUID := range version.Pages {
+		if err := s.pageVersions.Remove(ctx, pageUID, uid); err != nil {
+			return err
+		}
+	}
+	if err := s.search.DeleteVersion(ctx, uid); err != nil {
+		return err
+	}
+	return s.db.Delete(ctx, bucketVersions, uid.String())
+}
+
+// DeletePageIndex removes the page-versions index entry of a page that no longer exists.
+func (s versionStore) DeletePageIndex(ctx context.Context, pageUID ulid.ULID) error {
+	return s.pageVersions.Delete(ctx, pageUID)
+}
+
+// MarkPublished records that the version became the current version. The message is kept from a previous publish
+// when empty.
+func (s versionStore) MarkPublished(ctx context.Context, uid ulid.ULID, publishedBy string, message string) (core.Version, error) {
+	version, err := s.db.One(ctx, bucketVersions, uid.String())
+	if err != nil {
+		return core.Version{}, err
+	}
+	version.PublishedAt = time.Now().UTC()
+	version.PublishedBy = publishedBy
+	if message != "" {
+		version.Message = message
+	}
+	if err := s.db.Save(ctx, bucketVersions, uid.String(), version); err != nil {
+		return core.Version{}, err
+	}
+	return version, nil
+}
+
+func (s versionStore) SetRedirects(ctx context.Context, uid ulid.ULID, redirects []core.Redirect) (core.Version, error) {
+	version, err := s.db.One(ctx, bucketVersions, uid.String())
+	if err != nil {
+		return core.Version{}, err
+	}
+	version.Redirects = redirects
+	if err := s.db.Save(ctx, bucketVersions, uid.String(), version); err != nil {
+		return core.Version{}, err
+	}
+	return version, nil
+}
+
+func (s versionStore) SetBase(ctx context.Context, uid ulid.ULID, base ulid.ULID) (core.Version, error) {
+	version, err := s.db.One(ctx, bucketVersions, uid.String())
+	if err != nil {
+		return core.Version{}, err
+	}
+	version.Base = base
+	if err := s.db.Save(ctx, bucketVersions, uid.String(), version); err != nil {
+		return core.Version{}, err
+	}
+	return version, nil
+}
+
 func (s versionStore) SetPage(ctx context.Context, uid ulid.ULID, previousPath string, path string, pageUID ulid.ULID) (core.Version, error) {
 	version, err := s.db.One(ctx, bucketVersions, uid.String())
 	if err != nil {
@@ -75,15 +168,30 @@ func (s versionStore) UnsetPage(ctx context.Context, uid ulid.ULID, path string,
 	return version, nil
 }
 
-func (s versionStore) Clone(ctx context.Context, uid ulid.ULID) (core.Version, error) {
-	version, err := s.db.One(ctx, bucketVersions, uid.String())
+// Clone copies the pages and theme of a version into a new unpublished version based on it.
+func (s versionStore) Clone(ctx context.Context, uid ulid.ULID, createdBy string) (core.Version, error) {
+	source, err := s.db.One(ctx, bucketVersions, uid.String())
 	if err != nil {
 		return core.Version{}, err
 	}
-	version.UID = ulid.Make()
+	version := core.Version{
+		UID:       ulid.Make(),
+		Pages:     source.Pages,
+		Theme:     source.Theme,
+		Redirects: source.Redirects,
+		Base:      source.UID,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: createdBy,
+	}
 	if err := s.db.Save(ctx, bucketVersions, version.UID.String(), version); err != nil {
 		return core.Version{}, err
 	}
+	if err := s.pageVersions.CreateVersion(ctx, &version); err != nil {
+		return core.Version{}, err
+	}
+	if err := s.search.CopyVersion(ctx, source.UID, version.UID); err != nil {
+		return core.Version{}, err
+	}
 	return version, nil
 }
 
@@ -91,9 +199,10 @@ func (s versionStore) GetPageVersions(ctx context.Context, pageUID ulid.ULID) (m
 	return s.pageVersions.GetVersions(ctx, pageUID)
 }
 
-func NewVersionStore(db *bolt.DB, pageVersions PageVersionIndex) VersionStore {
+func NewVersionStore(db *bolt.DB, pageVersions PageVersionIndex, search SearchIndex) VersionStore {
 	return &versionStore{
 		db:           docDB[core.Version]{db},
 		pageVersions: pageVersions,
+		search:       search,
 	}
 }